@@ -0,0 +1,208 @@
+package jdcloud
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/jdcloud-api/jdcloud-sdk-go/services/charge/models"
+	"log"
+)
+
+/*
+	Billing of instance, disk, eip and rds are described with the same "charge" block
+	  charge {
+	    charge_mode     = "prepaid_by_duration"
+	    charge_unit     = "month"
+	    charge_duration = 3
+	  }
+	Only charge_mode can be read back, charge_unit and charge_duration are kept as configured.
+*/
+
+// Shared schema of "charge" block, modes are those supported by this resource
+func chargeSchema(modes ...string) *schema.Schema {
+
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Computed: true,
+		ForceNew: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"charge_mode": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Computed:     true,
+					ForceNew:     true,
+					ValidateFunc: validateStringCandidates(modes...),
+				},
+				"charge_unit": {
+					Type:             schema.TypeString,
+					Optional:         true,
+					ForceNew:         true,
+					ValidateFunc:     validateStringCandidates(CHARGE_UNIT_MONTH, CHARGE_UNIT_YEAR),
+					DiffSuppressFunc: chargeUnreadableDiffSuppress,
+				},
+				"charge_duration": {
+					Type:             schema.TypeInt,
+					Optional:         true,
+					ForceNew:         true,
+					DiffSuppressFunc: chargeUnreadableDiffSuppress,
+				},
+			},
+		},
+	}
+}
+
+// Same as chargeSchema, for resources that still come with deprecated top-level charge_mode/charge_unit/charge_duration
+// Only one of them can be used, the block would otherwise win silently
+func chargeSchemaWithLegacy(modes ...string) *schema.Schema {
+
+	s := chargeSchema(modes...)
+	s.ConflictsWith = []string{"charge_mode", "charge_unit", "charge_duration"}
+	return s
+}
+
+// charge_unit and charge_duration can not be read back,
+// They are missing on imported resources, which should not lead to a replacement
+func chargeUnreadableDiffSuppress(k, old, new string, d *schema.ResourceData) bool {
+	return d.Id() != "" && (old == "" || old == "0")
+}
+
+// Verify combinations of mode/unit/duration, used in CustomizeDiff
+//  1. prepaid_by_duration requires charge_duration, 1~9 for month, 1~3 for year
+//  2. postpaid modes accept neither charge_unit nor charge_duration
+func verifyChargeSpec(mode, unit string, duration int) error {
+
+	if mode != CHARGE_PREPAID_BY_DURATION {
+		if unit != "" || duration != 0 {
+			return fmt.Errorf("[ERROR] charge_unit and charge_duration can only be used when charge_mode is %s", CHARGE_PREPAID_BY_DURATION)
+		}
+		return nil
+	}
+
+	max := MAX_CHARGE_DURATION_MONTH
+	if unit == CHARGE_UNIT_YEAR {
+		max = MAX_CHARGE_DURATION_YEAR
+	}
+	if duration < 1 || duration > max {
+		return fmt.Errorf("[ERROR] charge_duration is required when charge_mode is %s, it varies from 1 to %d when charge_unit is %s", CHARGE_PREPAID_BY_DURATION, max, unit)
+	}
+	return nil
+}
+
+// CustomizeDiff of resources that have a "charge" block
+// Set legacy to true if this resource also comes with top-level charge_mode/charge_unit/charge_duration
+// Existing resources are verified as well when billing changes, as it leads to a replacement
+func chargeCustomizeDiff(key string, legacy bool) schema.CustomizeDiffFunc {
+	return func(d *schema.ResourceDiff, meta interface{}) error {
+
+		changed := d.HasChange(key)
+		if legacy {
+			changed = changed || d.HasChange("charge_mode") || d.HasChange("charge_unit") || d.HasChange("charge_duration")
+		}
+		if d.Id() != "" && !changed {
+			return nil
+		}
+
+		if v := d.Get(key).([]interface{}); len(v) > 0 && v[0] != nil {
+			c := v[0].(map[string]interface{})
+			if c["charge_mode"].(string) == "" {
+				return nil
+			}
+			return verifyChargeSpec(c["charge_mode"].(string), c["charge_unit"].(string), c["charge_duration"].(int))
+		}
+
+		if legacy && d.Get("charge_mode").(string) != "" {
+			return verifyChargeSpec(d.Get("charge_mode").(string), d.Get("charge_unit").(string), d.Get("charge_duration").(int))
+		}
+		return nil
+	}
+}
+
+// Convert "charge" block into ChargeSpec, nil will be returned if not specified
+func typeListToChargeSpec(v []interface{}) *models.ChargeSpec {
+
+	if len(v) == 0 || v[0] == nil {
+		return nil
+	}
+
+	c := v[0].(map[string]interface{})
+	if c["charge_mode"].(string) == "" {
+		return nil
+	}
+
+	spec := &models.ChargeSpec{ChargeMode: stringAddr(c["charge_mode"])}
+	if c["charge_mode"].(string) == CHARGE_PREPAID_BY_DURATION {
+		if c["charge_unit"].(string) != "" {
+			spec.ChargeUnit = stringAddr(c["charge_unit"])
+		}
+		duration := c["charge_duration"].(int)
+		spec.ChargeDuration = &duration
+	}
+	return spec
+}
+
+// Same as typeListToChargeSpec, falls back to top-level charge_mode/charge_unit/charge_duration
+func chargeSpecWithLegacy(d *schema.ResourceData) *models.ChargeSpec {
+
+	if spec := typeListToChargeSpec(d.Get("charge").([]interface{})); spec != nil {
+		return spec
+	}
+
+	if _, ok := d.GetOk("charge_mode"); !ok {
+		return nil
+	}
+	return typeListToChargeSpec([]interface{}{map[string]interface{}{
+		"charge_mode":     d.Get("charge_mode"),
+		"charge_unit":     d.Get("charge_unit"),
+		"charge_duration": d.Get("charge_duration"),
+	}})
+}
+
+// Convert Charge from remote into "charge" block, unit and duration are kept from current ones
+func chargeToTypeList(c models.Charge, current []interface{}) []interface{} {
+
+	m := map[string]interface{}{
+		"charge_mode":     c.ChargeMode,
+		"charge_unit":     "",
+		"charge_duration": 0,
+	}
+	if len(current) > 0 && current[0] != nil {
+		previous := current[0].(map[string]interface{})
+		m["charge_unit"] = previous["charge_unit"]
+		m["charge_duration"] = previous["charge_duration"]
+	}
+	return []interface{}{m}
+}
+
+// Resource level option on destroying prepaid resources, falls back to the provider one if not specified
+func prepaidOnDestroySchema() *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		ValidateFunc: validateStringCandidates(PREPAID_ON_DESTROY_FAIL, PREPAID_ON_DESTROY_ABANDON),
+	}
+}
+
+// Prepaid resources can not be deleted before they expire, decide what to do with them on destroy
+// Deletion should go ahead only if true is returned
+//  1. "fail"    -> Return an error immediately rather than retrying until timeout
+//  2. "abandon" -> Remove it from state and leave it to expire
+func prepaidDestroyAllowed(d *schema.ResourceData, meta interface{}, chargeMode string) (bool, error) {
+
+	if chargeMode != CHARGE_PREPAID_BY_DURATION {
+		return true, nil
+	}
+
+	behavior := meta.(*JDCloudConfig).PrepaidOnDestroy
+	if v, ok := d.GetOk("prepaid_on_destroy"); ok {
+		behavior = v.(string)
+	}
+
+	if behavior == PREPAID_ON_DESTROY_ABANDON {
+		log.Printf("[WARN] %s is prepaid and can not be deleted before it expires, it has been removed from state and left to expire", d.Id())
+		d.SetId("")
+		return false, nil
+	}
+	return false, fmt.Errorf("[ERROR] %s is prepaid and can not be deleted before it expires. Set prepaid_on_destroy to \"%s\" to remove it from state and leave it to expire", d.Id(), PREPAID_ON_DESTROY_ABANDON)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/jdcloud-api/jdcloud-sdk-go/core"
+	"time"
 )
 
 type (
@@ -12,6 +13,17 @@ type (
 		SecretKey  string
 		Region     string
 		Credential *core.Credential
+
+		// Behavior on destroying prepaid resources, can be overridden per resource
+		PrepaidOnDestroy string
+
+		// Waiters of each service share one poller, see batchPoller
+		PollInterval   time.Duration
+		instancePoller *batchPoller
+		diskPoller     *batchPoller
+
+		// Serialise operations on the same parent resource, see mutexKV
+		locks *mutexKV
 	}
 )
 
@@ -38,7 +50,13 @@ const (
 	REQUEST_INVALID    = 400
 	REQUEST_INVALID_2  = 403
 
+	DEFAULT_POLL_INTERVAL = 3
+	MIN_POLL_INTERVAL     = 1
+	MAX_POLL_INTERVAL     = 60
+	MAX_NOT_FOUND_CHECKS  = 20
+
 	MAX_DISK_COUNT          = 1
+	MAX_DISK_PAGE_SIZE      = 100
 	DISK_AVAILABLE          = "available"
 	DISK_DELETED            = "deleted"
 	DISK_DELETING           = "deleting"
@@ -46,8 +64,8 @@ const (
 	DISK_ATTACHING          = "attaching"
 	DISK_DETACHING          = "detaching"
 	DISK_INUSE              = "in-use"
-	DISK_TIMEOUT            = 60
-	DISK_ATTACHMENT_TIMEOUT = 60
+	DISK_TIMEOUT            = 120
+	DISK_ATTACHMENT_TIMEOUT = 120
 	DISK_ATTACHED           = "attached"
 	DISK_DETACHED           = "detached"
 
@@ -57,9 +75,14 @@ const (
 	DISK_ALREADY_ATTACHED              = "already attached"
 
 	MAX_EIP_COUNT     = 1
+	MAX_EIP_PAGE_SIZE = 100
 	MAX_SYSDISK_COUNT = 1
 	DISKTYPE_CLOUD    = "cloud"
+	DISKTYPE_LOCAL    = "local"
 	MAX_VM_COUNT      = 1
+	MAX_VM_BATCH      = 100
+	MAX_VM_PARALLEL   = 10
+	MAX_VM_PAGE_SIZE  = 100
 	VM_TIMEOUT        = 600
 	VM_PENDING        = "pending"
 	VM_STARTING       = "starting"
@@ -78,7 +101,19 @@ const (
 	KEYPAIRS_PERM = 0600
 	KEYPAIRS_PRIV = 0400
 
-	RDS_TIMEOUT       = 300
+	AG_SCALE_IN_OLDEST_FIRST = "oldest_first"
+	AG_SCALE_IN_AZ_BALANCE   = "az_balance"
+	MAX_AG_ROLLING_WAIT      = 3600
+
+	IMAGE_TIMEOUT  = 1800
+	IMAGE_PENDING  = "pending"
+	IMAGE_COPYING  = "copying"
+	IMAGE_READY    = "ready"
+	IMAGE_DELETING = "deleting"
+	IMAGE_DELETED  = ""
+	IMAGE_PRIVATE  = "private"
+
+	RDS_TIMEOUT       = 600
 	RDS_READY         = "RUNNING"
 	RDS_CREATING      = "BUILDING"
 	RDS_UNCERTAIN     = ""
@@ -86,8 +121,19 @@ const (
 	RDS_DELETED       = "DELETED"
 	RDS_UPDATING      = "MIGRATING"
 	RDS_MAX_RECONNECT = 6
+	RDS_PAGE_SIZE     = 100
 	CONNECT_FAILED    = "Client.Timeout exceeded"
 
+	CHARGE_PREPAID_BY_DURATION  = "prepaid_by_duration"
+	CHARGE_POSTPAID_BY_USAGE    = "postpaid_by_usage"
+	CHARGE_POSTPAID_BY_DURATION = "postpaid_by_duration"
+	CHARGE_UNIT_MONTH           = "month"
+	CHARGE_UNIT_YEAR            = "year"
+	MAX_CHARGE_DURATION_MONTH   = 9
+	MAX_CHARGE_DURATION_YEAR    = 3
+	PREPAID_ON_DESTROY_FAIL     = "fail"
+	PREPAID_ON_DESTROY_ABANDON  = "abandon"
+
 	DEFAULT_DEVICE_INDEX                  = 1
 	DEFAULT_NETWORK_INTERFACE_AUTO_DELETE = true
 	DEFAULT_SANITY_CHECK                  = 1
@@ -109,6 +155,15 @@ func initConfig(d *schema.ResourceData) (interface{}, error) {
 			d.Get("access_key").(string),
 			d.Get("secret_key").(string),
 		),
+		PrepaidOnDestroy: d.Get("prepaid_on_destroy").(string),
+		PollInterval:     time.Duration(d.Get("poll_interval").(int)) * time.Second,
+		locks:            newMutexKV(),
 	}
+	conf.instancePoller = newBatchPoller(conf.PollInterval, func(ids []string) (map[string]interface{}, error) {
+		return queryInstances(conf, ids)
+	})
+	conf.diskPoller = newBatchPoller(conf.PollInterval, func(ids []string) (map[string]interface{}, error) {
+		return queryDisks(conf, ids)
+	})
 	return conf, nil
 }
@@ -0,0 +1,30 @@
+package jdcloud
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// Guard stateful resources from being destroyed by mistake
+// It lives only in the state, hence turning it off takes a separate apply
+func deletionProtectionSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeBool,
+		Optional: true,
+		Default:  false,
+	}
+}
+
+// Call it in Read of importable resources, so that imported ones are recorded as unprotected
+// rather than missing the attribute
+func readDeletionProtection(d *schema.ResourceData) {
+	d.Set("deletion_protection", d.Get("deletion_protection").(bool))
+}
+
+// Call it before anything destructive happens in Delete
+func verifyDeletionProtection(d *schema.ResourceData) error {
+	if d.Get("deletion_protection").(bool) {
+		return fmt.Errorf("[ERROR] %s has deletion_protection enabled, set it to false and apply before destroying it", d.Id())
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package jdcloud
+
+import (
+	"log"
+	"sync"
+)
+
+// Operations on child resources sharing one parent conflict with each other, e.g. attaching
+// disks to the same instance, adding rules to the same route table. They are serialised
+// by taking the lock of their parent, keyed by its ID. Created once per provider, see initConfig
+type mutexKV struct {
+	lock  sync.Mutex
+	store map[string]*sync.Mutex
+}
+
+func newMutexKV() *mutexKV {
+	return &mutexKV{
+		store: map[string]*sync.Mutex{},
+	}
+}
+
+func (m *mutexKV) Lock(key string) {
+	log.Printf("[DEBUG] Locking %q", key)
+	m.get(key).Lock()
+	log.Printf("[DEBUG] Locked %q", key)
+}
+
+func (m *mutexKV) Unlock(key string) {
+	log.Printf("[DEBUG] Unlocking %q", key)
+	m.get(key).Unlock()
+	log.Printf("[DEBUG] Unlocked %q", key)
+}
+
+func (m *mutexKV) get(key string) *sync.Mutex {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	mutex, ok := m.store[key]
+	if !ok {
+		mutex = &sync.Mutex{}
+		m.store[key] = mutex
+	}
+	return mutex
+}
+
+// Lock the parent of a child resource, parents are instances, route tables,
+// security groups, network ACLs and RDS instances
+func lockParent(meta interface{}, parentId string) {
+	meta.(*JDCloudConfig).locks.Lock(parentId)
+}
+
+func unlockParent(meta interface{}, parentId string) {
+	meta.(*JDCloudConfig).locks.Unlock(parentId)
+}
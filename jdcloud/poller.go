@@ -0,0 +1,106 @@
+package jdcloud
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform/helper/resource"
+	"sync"
+	"time"
+)
+
+// Resources waiting on the same service share one batched Describe call per round,
+// rather than sending one call per resource. Created once per provider, see initConfig.
+// Resources missing from the result are reported as nil, which stands for deleted
+type batchPoller struct {
+	interval time.Duration
+	query    func(ids []string) (map[string]interface{}, error)
+
+	mu      sync.Mutex
+	waiters map[string][]chan batchReply
+}
+
+type batchReply struct {
+	item interface{}
+	err  error
+}
+
+func newBatchPoller(interval time.Duration, query func(ids []string) (map[string]interface{}, error)) *batchPoller {
+	return &batchPoller{
+		interval: interval,
+		query:    query,
+		waiters:  map[string][]chan batchReply{},
+	}
+}
+
+// Level 0 -> Block until next round finishes, the first waiter of a round schedules it
+func (p *batchPoller) get(id string) (interface{}, error) {
+
+	reply := make(chan batchReply, 1)
+
+	p.mu.Lock()
+	if len(p.waiters) == 0 {
+		time.AfterFunc(p.interval, p.round)
+	}
+	p.waiters[id] = append(p.waiters[id], reply)
+	p.mu.Unlock()
+
+	r := <-reply
+	return r.item, r.err
+}
+
+func (p *batchPoller) round() {
+
+	p.mu.Lock()
+	waiters := p.waiters
+	p.waiters = map[string][]chan batchReply{}
+	p.mu.Unlock()
+
+	ids := make([]string, 0, len(waiters))
+	for id := range waiters {
+		ids = append(ids, id)
+	}
+
+	items, err := p.query(ids)
+	for id, replies := range waiters {
+		for _, reply := range replies {
+			reply <- batchReply{item: items[id], err: err}
+		}
+	}
+}
+
+// Level 1 -> Wait until refresh reports one of target status
+// Refresh functions backed by a batchPoller are paced by it, hence no extra sleep in between
+// Similar to StateChangeConf, a nil item is tolerated for a few rounds unless its status is expected,
+// newly created resources may not show up in batched queries immediately
+func waitForRefresh(id string, refresh resource.StateRefreshFunc, pending, target []string, timeout time.Duration) error {
+
+	deadline := time.Now().Add(timeout)
+	notFound := 0
+	for {
+		item, status, err := refresh()
+		if err != nil {
+			return err
+		}
+		if stringInSlice(status, target) {
+			return nil
+		}
+		if item == nil {
+			if notFound++; notFound > MAX_NOT_FOUND_CHECKS {
+				return fmt.Errorf("[ERROR] %s can not be found after %d attempts", id, notFound)
+			}
+		} else if !stringInSlice(status, pending) {
+			return fmt.Errorf("[ERROR] %s reached unexpected status \"%s\", expecting %v", id, status, target)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("[ERROR] Timeout after %s waiting %s to reach %v, last status \"%s\"", timeout, id, target, status)
+		}
+	}
+}
+
+func stringInSlice(s string, list []string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -32,6 +32,10 @@ func Provider() *schema.Provider {
 			"jdcloud_availability_group":           resourceJDCloudAvailabilityGroup(),
 			"jdcloud_instance_template":            resourceJDCloudInstanceTemplate(),
 			"jdcloud_instance_ag_instance":         resourceJDCloudAGInstance(),
+			"jdcloud_instance_group":               resourceJDCloudInstanceGroup(),
+			"jdcloud_image":                        resourceJDCloudImage(),
+			"jdcloud_image_copy":                   resourceJDCloudImageCopy(),
+			"jdcloud_image_share":                  resourceJDCloudImageShare(),
 		},
 		Schema: map[string]*schema.Schema{
 			"access_key": &schema.Schema{
@@ -52,6 +56,20 @@ func Provider() *schema.Provider {
 				DefaultFunc: schema.EnvDefaultFunc("region", nil),
 				Description: "The region where JDCLOUD operations will take place",
 			},
+			"prepaid_on_destroy": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      PREPAID_ON_DESTROY_FAIL,
+				ValidateFunc: validateStringCandidates(PREPAID_ON_DESTROY_FAIL, PREPAID_ON_DESTROY_ABANDON),
+				Description:  "What to do when destroying prepaid resources that can not be deleted before they expire, fail or abandon",
+			},
+			"poll_interval": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      DEFAULT_POLL_INTERVAL,
+				ValidateFunc: validateIntRange(MIN_POLL_INTERVAL, MAX_POLL_INTERVAL),
+				Description:  "Seconds between two rounds of status polling while waiting for resources",
+			},
 		},
 		ConfigureFunc: initConfig,
 	}
@@ -28,6 +28,12 @@ func resourceJDCloudAGInstance() *schema.Resource {
 		Update: resourceJDCloudAGInstanceUpdate,
 		Delete: resourceJDCloudAGInstanceDelete,
 
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(VM_TIMEOUT * time.Second),
+			Update: schema.DefaultTimeout(VM_TIMEOUT * time.Second),
+			Delete: schema.DefaultTimeout(VM_TIMEOUT * time.Second),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"availability_group_id": {
 				Type:     schema.TypeString,
@@ -63,7 +69,7 @@ func resourceJDCloudAGInstance() *schema.Resource {
 func resourceJDCloudAGInstanceCreate(d *schema.ResourceData, m interface{}) error {
 
 	agId := d.Get("availability_group_id").(string)
-	if e := createAgInstances(d, m, agId, d.Get("instances").(*schema.Set)); e != nil {
+	if e := createAgInstances(d, m, agId, d.Get("instances").(*schema.Set), d.Timeout(schema.TimeoutCreate)); e != nil {
 		return e
 	}
 	d.SetId(agId)
@@ -126,7 +132,7 @@ func resourceJDCloudAGInstanceUpdate(d *schema.ResourceData, m interface{}) erro
 		detach := previousSet.Difference(intersect)
 		if len(detach.List()) > 0 {
 			ids := getIdLists(detach)
-			if e := deleteInstances(d, m, ids); e != nil {
+			if e := deleteInstances(d, m, ids, d.Timeout(schema.TimeoutUpdate)); e != nil {
 				return fmt.Errorf("AGInstance Update Failed in detaching, %v", e)
 			}
 		}
@@ -135,7 +141,7 @@ func resourceJDCloudAGInstanceUpdate(d *schema.ResourceData, m interface{}) erro
 		// Perform attaching
 		attach := currentSet.Difference(intersect)
 		if len(attach.List()) > 0 {
-			if e := createAgInstances(d, m, d.Id(), attach); e != nil {
+			if e := createAgInstances(d, m, d.Id(), attach, d.Timeout(schema.TimeoutUpdate)); e != nil {
 				return e
 			}
 		}
@@ -156,14 +162,14 @@ func getIdLists(set *schema.Set) (ids []string) {
 }
 
 // Level-0 Send requests only
-func agInstancesSendRequests(m interface{}, reqs []*apis.CreateInstancesRequest) (instanceIds []string, errs []error) {
+func agInstancesSendRequests(m interface{}, reqs []*apis.CreateInstancesRequest, timeout time.Duration) (instanceIds []string, errs []error) {
 
 	config := m.(*JDCloudConfig)
 	vmClient := client.NewVmClient(config.Credential)
 
 	for _, req := range reqs {
 
-		e := resource.Retry(2*time.Minute, func() *resource.RetryError {
+		e := resource.Retry(timeout, func() *resource.RetryError {
 
 			resp, err := vmClient.CreateInstances(req)
 			if err == nil && resp.Error.Code == REQUEST_COMPLETED {
@@ -185,7 +191,7 @@ func agInstancesSendRequests(m interface{}, reqs []*apis.CreateInstancesRequest)
 }
 
 // Level-0 Create some instances
-func createAgInstances(d *schema.ResourceData, m interface{}, agId string, set *schema.Set) error {
+func createAgInstances(d *schema.ResourceData, m interface{}, agId string, set *schema.Set, timeout time.Duration) error {
 
 	// Send some requests
 	reqs := []*apis.CreateInstancesRequest{}
@@ -197,16 +203,17 @@ func createAgInstances(d *schema.ResourceData, m interface{}, agId string, set *
 			Name:        itemMap["instance_name"].(string),
 			Description: stringAddr(itemMap["description"].(string)),
 		})
+		req.SetClientToken(clientTokenDefault())
 		reqs = append(reqs, req)
 	}
-	instanceIds, errs := agInstancesSendRequests(m, reqs)
+	instanceIds, errs := agInstancesSendRequests(m, reqs, timeout)
 	if len(errs) > 0 {
 		return errs[0]
 	}
 
 	// Waiting until VMs are ready
 	for _, instanceId := range instanceIds {
-		if e := instanceStatusWaiter(d, m, instanceId, []string{VM_PENDING, VM_STARTING}, []string{VM_RUNNING}); e != nil {
+		if e := instanceStatusWaiter(d, m, instanceId, []string{VM_PENDING, VM_STARTING}, []string{VM_RUNNING}, timeout); e != nil {
 			errs = append(errs, e)
 		}
 
@@ -223,7 +230,7 @@ func resourceJDCloudAGInstanceDelete(d *schema.ResourceData, m interface{}) erro
 
 	ids := getIdLists(d.Get("instances").(*schema.Set))
 
-	if e := deleteInstances(d, m, ids); e != nil {
+	if e := deleteInstances(d, m, ids, d.Timeout(schema.TimeoutDelete)); e != nil {
 		return e
 	}
 
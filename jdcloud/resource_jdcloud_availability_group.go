@@ -1,13 +1,324 @@
 package jdcloud
 
 import (
+	"fmt"
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/ag/apis"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/ag/client"
+	common "github.com/jdcloud-api/jdcloud-sdk-go/services/common/models"
+	vmApis "github.com/jdcloud-api/jdcloud-sdk-go/services/vm/apis"
+	vmClient "github.com/jdcloud-api/jdcloud-sdk-go/services/vm/client"
+	vm "github.com/jdcloud-api/jdcloud-sdk-go/services/vm/models"
+	"log"
+	"sort"
 	"time"
 )
 
+/*
+	Members of an availability group can be managed by desired_capacity, rather than listing
+	them one by one in jdcloud_instance_ag_instance. Do not use both on the same group.
+	Members are launched from the instance template of this group, named after this group.
+*/
+
+//----------------------------------------------------------------------------------- OTHERS
+
+// Level 0 -> Query members of an availability group, oldest first
+func queryAgInstances(m interface{}, agId string) ([]vm.Instance, error) {
+
+	config := m.(*JDCloudConfig)
+	c := vmClient.NewVmClient(config.Credential)
+	instances := []vm.Instance{}
+
+	for page := 1; ; page++ {
+
+		req := vmApis.NewDescribeInstancesRequestWithAllParams(config.Region, &page, intAddr(MAX_VM_PAGE_SIZE), []common.Filter{
+			{Name: "agId", Values: []string{agId}},
+		})
+
+		total := 0
+		e := resource.Retry(time.Minute, func() *resource.RetryError {
+
+			resp, err := c.DescribeInstances(req)
+
+			if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+				for _, instance := range resp.Result.Instances {
+					// Members being deleted are not counted
+					if instance.Status != VM_DELETING && instance.Status != VM_TERMINATED {
+						instances = append(instances, instance)
+					}
+				}
+				total = resp.Result.TotalCount
+				return nil
+			}
+
+			if connectionError(err) {
+				return resource.RetryableError(formatConnectionErrorMessage())
+			} else {
+				return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+			}
+		})
+		if e != nil {
+			return nil, e
+		}
+		if page*MAX_VM_PAGE_SIZE >= total {
+			break
+		}
+	}
+
+	// Launch time is reported in the same format, comparing strings is enough
+	sort.SliceStable(instances, func(i, j int) bool {
+		return instances[i].LaunchTime < instances[j].LaunchTime
+	})
+	return instances, nil
+}
+
+// Level 0 -> Pick members to be removed on scaling in, instances are sorted oldest first
+//  1. "oldest_first" -> The oldest ones
+//  2. "az_balance"   -> The oldest one in the availability zone with most members, one after another
+func agScaleInCandidates(instances []vm.Instance, n int, policy string) []string {
+
+	ids := []string{}
+	if policy != AG_SCALE_IN_AZ_BALANCE {
+		for _, instance := range instances[:n] {
+			ids = append(ids, instance.InstanceId)
+		}
+		return ids
+	}
+
+	azs := []string{}
+	members := map[string][]vm.Instance{}
+	for _, instance := range instances {
+		if _, ok := members[instance.Az]; !ok {
+			azs = append(azs, instance.Az)
+		}
+		members[instance.Az] = append(members[instance.Az], instance)
+	}
+	sort.Strings(azs)
+
+	for len(ids) < n {
+		pick := azs[0]
+		for _, az := range azs {
+			if len(members[az]) > len(members[pick]) {
+				pick = az
+			}
+		}
+		ids = append(ids, members[pick][0].InstanceId)
+		members[pick] = members[pick][1:]
+	}
+	return ids
+}
+
+// Level 0 -> Launch some members in a single request, the template of this group supplies the spec
+func createAgMembers(m interface{}, agId, name string, count int, timeout time.Duration) (instanceIds []string, err error) {
+
+	config := m.(*JDCloudConfig)
+	c := vmClient.NewVmClient(config.Credential)
+
+	req := vmApis.NewCreateInstancesRequest(config.Region, &vm.InstanceSpec{AgId: &agId, Name: name})
+	req.SetMaxCount(count)
+	req.SetClientToken(clientTokenDefault())
+
+	err = resource.Retry(timeout, func() *resource.RetryError {
+
+		resp, err := c.CreateInstances(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			instanceIds = resp.Result.InstanceIds
+			return nil
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+	return
+}
+
+// Level 0 -> Remove some members from an availability group, they are kept as standalone instances
+func abandonAgInstances(m interface{}, agId string, instanceIds []string) error {
+
+	config := m.(*JDCloudConfig)
+	agClient := client.NewAgClient(config.Credential)
+	req := apis.NewAbandonInstancesRequest(config.Region, agId, instanceIds)
+
+	return resource.Retry(2*time.Minute, func() *resource.RetryError {
+
+		resp, err := agClient.AbandonInstances(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			return nil
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+}
+
+// Level 1 -> Launch some members and wait until they are running
+func launchAgMembers(d *schema.ResourceData, m interface{}, count int, deadline time.Time) ([]string, error) {
+
+	instanceIds, err := createAgMembers(m, d.Id(), d.Get("availability_group_name").(string), count, time.Until(deadline))
+	if err != nil {
+		return nil, err
+	}
+	return instanceIds, waitForInstancesRunning(d, m, instanceIds, time.Until(deadline))
+}
+
+// Level 2~3 -> Remove some members, they are deleted unless abandon_on_scale_in is set
+func removeAgMembers(d *schema.ResourceData, m interface{}, instanceIds []string, deadline time.Time) error {
+
+	if d.Get("abandon_on_scale_in").(bool) {
+		return abandonAgInstances(m, d.Id(), instanceIds)
+	}
+	return deleteInstances(d, m, instanceIds, time.Until(deadline))
+}
+
+// Level 2 -> Launch or remove members until there are desired of them
+func scaleAvailabilityGroup(d *schema.ResourceData, m interface{}, desired int, timeout time.Duration) error {
+
+	deadline := time.Now().Add(timeout)
+
+	instances, err := queryAgInstances(m, d.Id())
+	if err != nil {
+		return err
+	}
+
+	if desired > len(instances) {
+		log.Printf("[INFO] Scaling out %s from %d to %d members", d.Id(), len(instances), desired)
+		_, err := launchAgMembers(d, m, desired-len(instances), deadline)
+		return err
+	}
+
+	if desired < len(instances) {
+		log.Printf("[INFO] Scaling in %s from %d to %d members", d.Id(), len(instances), desired)
+		return removeAgMembers(d, m, agScaleInCandidates(instances, len(instances)-desired, d.Get("scale_in_policy").(string)), deadline)
+	}
+
+	return nil
+}
+
+// Level 0 -> Switch the instance template, members launched afterwards are built from the new one
+func setAgInstanceTemplate(m interface{}, agId, templateId string) error {
+
+	config := m.(*JDCloudConfig)
+	agClient := client.NewAgClient(config.Credential)
+	req := apis.NewSetInstanceTemplateRequest(config.Region, agId, templateId)
+
+	return resource.Retry(2*time.Minute, func() *resource.RetryError {
+
+		resp, err := agClient.SetInstanceTemplate(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			return nil
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+}
+
+// Level 1 -> Filter out those no longer members of this group, the order is kept
+func currentAgMembers(m interface{}, agId string, instanceIds []string) ([]string, error) {
+
+	instances, err := queryAgInstances(m, agId)
+	if err != nil {
+		return nil, err
+	}
+	members := map[string]bool{}
+	for _, instance := range instances {
+		members[instance.InstanceId] = true
+	}
+
+	current := []string{}
+	for _, id := range instanceIds {
+		if members[id] {
+			current = append(current, id)
+		}
+	}
+	return current, nil
+}
+
+// Level 0 -> Sleep for a while, but no later than deadline
+func sleepBefore(duration time.Duration, deadline time.Time) {
+
+	if left := time.Until(deadline); duration > left {
+		duration = left
+	}
+	if duration > 0 {
+		time.Sleep(duration)
+	}
+}
+
+// Level 3 -> Replace members listed in outdated_instance_ids batch by batch, following rolling_update.
+// Replacements of a batch are launched before the old members are deleted, unless max_unavailable
+// allows the whole batch to be down. Old members are always deleted, abandon_on_scale_in only applies to scaling in.
+// On failure it stops, members not yet replaced stay outdated
+func rollAvailabilityGroup(d *schema.ResourceData, m interface{}, timeout time.Duration) error {
+
+	deadline := time.Now().Add(timeout)
+	policy := d.Get("rolling_update").([]interface{})[0].(map[string]interface{})
+	batchSize := policy["batch_size"].(int)
+	maxUnavailable := policy["max_unavailable"].(int)
+	pause := time.Duration(policy["pause_between_batches"].(int)) * time.Second
+	grace := time.Duration(policy["health_check_grace_period"].(int)) * time.Second
+
+	outdated, err := currentAgMembers(m, d.Id(), typeListToStringArray(d.Get("outdated_instance_ids").([]interface{})))
+	if err != nil {
+		return err
+	}
+	batches := (len(outdated) + batchSize - 1) / batchSize
+
+	for i := 0; len(outdated) > 0; i++ {
+
+		batch := outdated
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		log.Printf("[INFO] Rolling update of %s, batch %d/%d, replacing %v", d.Id(), i+1, batches, batch)
+
+		if len(batch) <= maxUnavailable {
+			if err := deleteInstances(d, m, batch, time.Until(deadline)); err != nil {
+				return fmt.Errorf("[ERROR] Rolling update of %s aborted in batch %d/%d, failed in deleting %v: %v", d.Id(), i+1, batches, batch, err)
+			}
+		}
+
+		replacements, err := launchAgMembers(d, m, len(batch), deadline)
+		if err != nil {
+			return fmt.Errorf("[ERROR] Rolling update of %s aborted in batch %d/%d, failed in launching replacements %v: %v", d.Id(), i+1, batches, replacements, err)
+		}
+		sleepBefore(grace, deadline)
+
+		if len(batch) > maxUnavailable {
+			if err := deleteInstances(d, m, batch, time.Until(deadline)); err != nil {
+				return fmt.Errorf("[ERROR] Rolling update of %s aborted in batch %d/%d, failed in deleting %v: %v", d.Id(), i+1, batches, batch, err)
+			}
+		}
+
+		outdated = outdated[len(batch):]
+		if err := d.Set("outdated_instance_ids", outdated); err != nil {
+			return err
+		}
+		d.SetPartial("outdated_instance_ids")
+		log.Printf("[INFO] Rolling update of %s, batch %d/%d finished, %d members left", d.Id(), i+1, batches, len(outdated))
+
+		if len(outdated) > 0 {
+			sleepBefore(pause, deadline)
+		}
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------------------- RESOURCE
+
 func resourceJDCloudAvailabilityGroup() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceJDCloudAvailabilityGroupCreate,
@@ -15,6 +326,14 @@ func resourceJDCloudAvailabilityGroup() *schema.Resource {
 		Update: resourceJDCloudAvailabilityGroupUpdate,
 		Delete: resourceJDCloudAvailabilityGroupDelete,
 
+		CustomizeDiff: resourceJDCloudAvailabilityGroupCustomizeDiff,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(VM_TIMEOUT * time.Second),
+			Delete: schema.DefaultTimeout(VM_TIMEOUT * time.Second),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"availability_group_name": &schema.Schema{
 				Type:     schema.TypeString,
@@ -29,10 +348,10 @@ func resourceJDCloudAvailabilityGroup() *schema.Resource {
 				MinItems: 1,
 				ForceNew: true,
 			},
+			// Existing members are replaced only if rolling_update is specified
 			"instance_template_id": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
-				ForceNew: true,
 			},
 			"ag_type": &schema.Schema{
 				Type:     schema.TypeString,
@@ -44,10 +363,85 @@ func resourceJDCloudAvailabilityGroup() *schema.Resource {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
+			// Members are managed only while it is specified, it is left out of state otherwise
+			"desired_capacity": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validateIntRange(0, MAX_VM_BATCH),
+			},
+			"scale_in_policy": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      AG_SCALE_IN_OLDEST_FIRST,
+				ValidateFunc: validateStringCandidates(AG_SCALE_IN_OLDEST_FIRST, AG_SCALE_IN_AZ_BALANCE),
+			},
+			"abandon_on_scale_in": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"instance_ids": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"rolling_update": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"batch_size": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      1,
+							ValidateFunc: validateIntRange(1, MAX_VM_BATCH),
+						},
+						"max_unavailable": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      0,
+							ValidateFunc: validateIntRange(0, MAX_VM_BATCH),
+						},
+						"pause_between_batches": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      0,
+							ValidateFunc: validateIntRange(0, MAX_AG_ROLLING_WAIT),
+						},
+						"health_check_grace_period": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      0,
+							ValidateFunc: validateIntRange(0, MAX_AG_ROLLING_WAIT),
+						},
+					},
+				},
+			},
+			// Members launched before instance_template_id was last modified, and not yet replaced
+			"outdated_instance_ids": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
 		},
 	}
 }
 
+func resourceJDCloudAvailabilityGroupCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+
+	if d.Id() == "" {
+		return nil
+	}
+
+	// Outdated members left by an aborted rolling update are picked up on next apply
+	_, rolling := d.GetOk("rolling_update")
+	if d.HasChange("instance_template_id") || (rolling && len(d.Get("outdated_instance_ids").([]interface{})) > 0) {
+		return d.SetNewComputed("outdated_instance_ids")
+	}
+	return nil
+}
+
 func resourceJDCloudAvailabilityGroupCreate(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*JDCloudConfig)
 
@@ -63,7 +457,7 @@ func resourceJDCloudAvailabilityGroupCreate(d *schema.ResourceData, meta interfa
 
 	agClient := client.NewAgClient(config.Credential)
 
-	err := resource.Retry(10*time.Minute, func() *resource.RetryError {
+	err := resource.Retry(d.Timeout(schema.TimeoutCreate), func() *resource.RetryError {
 
 		resp, err := agClient.CreateAg(req)
 		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
@@ -81,16 +475,44 @@ func resourceJDCloudAvailabilityGroupCreate(d *schema.ResourceData, meta interfa
 		return err
 	}
 
+	if _, ok := d.GetOk("desired_capacity"); ok {
+		if err := scaleAvailabilityGroup(d, meta, d.Get("desired_capacity").(int), d.Timeout(schema.TimeoutCreate)); err != nil {
+			return err
+		}
+	}
+
 	return resourceJDCloudAvailabilityGroupRead(d, meta)
 }
 
 func resourceJDCloudAvailabilityGroupDelete(d *schema.ResourceData, meta interface{}) error {
 
+	deadline := time.Now().Add(d.Timeout(schema.TimeoutDelete))
+
+	// A group with members can not be deleted. Members managed by desired_capacity are removed
+	// the same way as scaling in to 0, others are never deleted behind the back of their owner
+	instances, err := queryAgInstances(meta, d.Id())
+	if err != nil {
+		return err
+	}
+	if len(instances) > 0 {
+		instanceIds := []string{}
+		for _, instance := range instances {
+			instanceIds = append(instanceIds, instance.InstanceId)
+		}
+		if _, ok := d.GetOkExists("desired_capacity"); !ok {
+			return fmt.Errorf("[ERROR] %s still has members %v, remove them or manage them by desired_capacity before destroying it", d.Id(), instanceIds)
+		}
+		log.Printf("[INFO] Removing members %v before deleting %s", instanceIds, d.Id())
+		if err := removeAgMembers(d, meta, instanceIds, deadline); err != nil {
+			return err
+		}
+	}
+
 	config := meta.(*JDCloudConfig)
 	req := apis.NewDeleteAgRequest(config.Region, d.Id())
 	agClient := client.NewAgClient(config.Credential)
 
-	err := resource.Retry(2*time.Minute, func() *resource.RetryError {
+	err = resource.Retry(time.Until(deadline), func() *resource.RetryError {
 
 		resp, err := agClient.DeleteAg(req)
 
@@ -146,9 +568,32 @@ func resourceJDCloudAvailabilityGroupRead(d *schema.ResourceData, meta interface
 		}
 	})
 
+	if err != nil || d.Id() == "" {
+		return err
+	}
+
+	instances, err := queryAgInstances(meta, d.Id())
 	if err != nil {
 		return err
 	}
+	instanceIds := []string{}
+	for _, instance := range instances {
+		instanceIds = append(instanceIds, instance.InstanceId)
+	}
+	if _, ok := d.GetOkExists("desired_capacity"); ok {
+		d.Set("desired_capacity", len(instanceIds))
+	}
+	if err := d.Set("instance_ids", instanceIds); err != nil {
+		return fmt.Errorf("[ERROR] Failed in setting instance_ids, reasons:%s", err.Error())
+	}
+
+	outdated, err := currentAgMembers(meta, d.Id(), typeListToStringArray(d.Get("outdated_instance_ids").([]interface{})))
+	if err != nil {
+		return err
+	}
+	if err := d.Set("outdated_instance_ids", outdated); err != nil {
+		return fmt.Errorf("[ERROR] Failed in setting outdated_instance_ids, reasons:%s", err.Error())
+	}
 
 	return nil
 }
@@ -182,10 +627,57 @@ func resourceJDCloudAvailabilityGroupUpdate(d *schema.ResourceData, meta interfa
 			}
 		})
 
+		if err != nil || d.Id() == "" {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(d.Timeout(schema.TimeoutUpdate))
+	d.Partial(true)
+	d.SetPartial("availability_group_name")
+	d.SetPartial("description")
+
+	// It is planned as computed, keep the recorded value unless the template is switched below
+	outdated, _ := d.GetChange("outdated_instance_ids")
+	if err := d.Set("outdated_instance_ids", outdated); err != nil {
+		return err
+	}
+	d.SetPartial("outdated_instance_ids")
+
+	// Members launched so far are all built from previous templates
+	if d.HasChange("instance_template_id") {
+		if err := setAgInstanceTemplate(meta, d.Id(), d.Get("instance_template_id").(string)); err != nil {
+			return err
+		}
+		instances, err := queryAgInstances(meta, d.Id())
 		if err != nil {
 			return err
 		}
+		outdated := []string{}
+		for _, instance := range instances {
+			outdated = append(outdated, instance.InstanceId)
+		}
+		if err := d.Set("outdated_instance_ids", outdated); err != nil {
+			return err
+		}
+		d.SetPartial("outdated_instance_ids")
+		d.SetPartial("instance_template_id")
+	}
+
+	// Members are left as they are once desired_capacity is removed
+	if _, ok := d.GetOkExists("desired_capacity"); ok && d.HasChange("desired_capacity") {
+		if err := scaleAvailabilityGroup(d, meta, d.Get("desired_capacity").(int), time.Until(deadline)); err != nil {
+			return err
+		}
+		d.SetPartial("desired_capacity")
+	}
+
+	if _, ok := d.GetOk("rolling_update"); ok {
+		if err := rollAvailabilityGroup(d, meta, time.Until(deadline)); err != nil {
+			return err
+		}
 	}
 
+	d.Partial(false)
 	return resourceJDCloudAvailabilityGroupRead(d, meta)
 }
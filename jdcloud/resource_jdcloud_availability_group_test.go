@@ -81,6 +81,168 @@ func TestAccJDCloudAvailabilityGroup_basic(t *testing.T) {
 	})
 }
 
+const testAccAGCapacityTemplate = `
+resource "jdcloud_availability_group" "terraform_ag" {
+  availability_group_name = "%s"
+  az = ["cn-north-1c"]
+  instance_template_id = "%s"
+  desired_capacity = %d
+  scale_in_policy = "az_balance"
+}
+`
+
+// Members are launched and removed to match desired_capacity, scaled to 0 in the end so that it can be destroyed
+func TestAccJDCloudAvailabilityGroup_desiredCapacity(t *testing.T) {
+
+	var agId string
+	name := randomStringWithLength(10)
+
+	resource.Test(t, resource.TestCase{
+
+		IDRefreshName: "jdcloud_availability_group.terraform_ag",
+		PreCheck:      func() { testAccPreCheck(t) },
+		Providers:     testAccProviders,
+		CheckDestroy:  testAccIfAgDestroyed(&agId),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccAGCapacityTemplate, name, packer_template, 2),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfAgExists("jdcloud_availability_group.terraform_ag", &agId),
+					testAccIfAgHasMembers(&agId, 2),
+					resource.TestCheckResourceAttr(
+						"jdcloud_availability_group.terraform_ag", "instance_ids.#", "2"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccAGCapacityTemplate, name, packer_template, 1),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfAgHasMembers(&agId, 1),
+					resource.TestCheckResourceAttr(
+						"jdcloud_availability_group.terraform_ag", "instance_ids.#", "1"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccAGCapacityTemplate, name, packer_template, 0),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfAgHasMembers(&agId, 0),
+				),
+			},
+		},
+	})
+}
+
+const testAccAGRollingTemplate = `
+resource "jdcloud_instance_template" "rolling" {
+  template_name = "%s"
+  instance_type = "g.n2.medium"
+  image_id = "%s"
+  password = "DevOps2018"
+  subnet_id = "%s"
+  security_group_ids = ["%s"]
+  system_disk  {
+    disk_category = "local"
+  }
+}
+
+resource "jdcloud_availability_group" "terraform_ag" {
+  availability_group_name = "%s"
+  az = ["cn-north-1c"]
+  instance_template_id = "%s"
+  desired_capacity = %d
+  rolling_update {
+    batch_size = 1
+    max_unavailable = 0
+  }
+}
+`
+
+func agRollingConfig(name, templateId string, capacity int) string {
+	return fmt.Sprintf(testAccAGRollingTemplate, name, packer_image, packer_subnet, packer_sg, name, templateId, capacity)
+}
+
+// Members are replaced one by one after the template is switched, scaled to 0 in the end so that it can be destroyed
+func TestAccJDCloudAvailabilityGroup_rollingUpdate(t *testing.T) {
+
+	var agId string
+	var before []string
+	name := randomStringWithLength(10)
+
+	resource.Test(t, resource.TestCase{
+
+		IDRefreshName: "jdcloud_availability_group.terraform_ag",
+		PreCheck:      func() { testAccPreCheck(t) },
+		Providers:     testAccProviders,
+		CheckDestroy:  testAccIfAgDestroyed(&agId),
+		Steps: []resource.TestStep{
+			{
+				Config: agRollingConfig(name, packer_template, 2),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfAgExists("jdcloud_availability_group.terraform_ag", &agId),
+					testAccAgMembers(&agId, &before),
+				),
+			},
+			{
+				Config: agRollingConfig(name, "${jdcloud_instance_template.rolling.id}", 2),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfAgHasMembers(&agId, 2),
+					testAccIfAgMembersReplaced(&agId, &before),
+					resource.TestCheckResourceAttr(
+						"jdcloud_availability_group.terraform_ag", "outdated_instance_ids.#", "0"),
+				),
+			},
+			{
+				Config: agRollingConfig(name, "${jdcloud_instance_template.rolling.id}", 0),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfAgHasMembers(&agId, 0),
+				),
+			},
+		},
+	})
+}
+
+func testAccAgMembers(agId *string, instanceIds *[]string) resource.TestCheckFunc {
+	return func(stateInfo *terraform.State) error {
+
+		instances, err := queryAgInstances(testAccProvider.Meta(), *agId)
+		if err != nil {
+			return err
+		}
+		*instanceIds = []string{}
+		for _, instance := range instances {
+			*instanceIds = append(*instanceIds, instance.InstanceId)
+		}
+		return nil
+	}
+}
+
+func testAccIfAgMembersReplaced(agId *string, before *[]string) resource.TestCheckFunc {
+	return func(stateInfo *terraform.State) error {
+
+		current, err := currentAgMembers(testAccProvider.Meta(), *agId, *before)
+		if err != nil {
+			return err
+		}
+		if len(current) != 0 {
+			return fmt.Errorf("[ERROR] testAccIfAgMembersReplaced failed, %v are not replaced", current)
+		}
+		return nil
+	}
+}
+
+func testAccIfAgHasMembers(agId *string, count int) resource.TestCheckFunc {
+	return func(stateInfo *terraform.State) error {
+
+		instances, err := queryAgInstances(testAccProvider.Meta(), *agId)
+		if err != nil {
+			return err
+		}
+		if len(instances) != count {
+			return fmt.Errorf("[ERROR] testAccIfAgHasMembers failed, expecting %d members, got %d", count, len(instances))
+		}
+		return nil
+	}
+}
+
 func testAccIfAgExists(agName string, agId *string) resource.TestCheckFunc {
 
 	return func(stateInfo *terraform.State) error {
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
-	"github.com/jdcloud-api/jdcloud-sdk-go/services/charge/models"
+	common "github.com/jdcloud-api/jdcloud-sdk-go/services/common/models"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/disk/apis"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/disk/client"
 	disk "github.com/jdcloud-api/jdcloud-sdk-go/services/disk/models"
@@ -13,39 +13,66 @@ import (
 
 //---------------------------------------------------------------------------------	DISK-SCHEMA-HELPERS
 
-// This function will return the latest status of a disk, level 0
+// This function will return the latest status of a disk, level 0 -> Based on the disk poller shared by this provider
+// Deleted disks are reported as nil with status DISK_DELETED, disks whose charge is not ready yet are reported as nil
 func diskStatusRefreshFunc(d *schema.ResourceData, meta interface{}, diskId string) resource.StateRefreshFunc {
 
 	return func() (diskItem interface{}, diskState string, e error) {
 
-		err := resource.Retry(time.Minute, func() *resource.RetryError {
+		config := meta.(*JDCloudConfig)
+		item, err := config.diskPoller.get(diskId)
+		if err != nil {
+			return nil, "", err
+		}
+		if item == nil {
+			return nil, DISK_DELETED, nil
+		}
+		if item.(disk.Disk).Charge.ChargeMode == "" {
+			return nil, "", nil
+		}
+		return item, item.(disk.Disk).Status, nil
+	}
+}
+
+// Query disks in batches, used by the disk poller. Deleted ones may be missing from the result, level 0
+func queryDisks(meta interface{}, diskIds []string) (map[string]interface{}, error) {
+
+	config := meta.(*JDCloudConfig)
+	c := client.NewDiskClient(config.Credential)
+	disks := make(map[string]interface{}, len(diskIds))
+
+	for start := 0; start < len(diskIds); start += MAX_DISK_PAGE_SIZE {
+
+		end := start + MAX_DISK_PAGE_SIZE
+		if end > len(diskIds) {
+			end = len(diskIds)
+		}
+		req := apis.NewDescribeDisksRequestWithAllParams(config.Region, nil, intAddr(MAX_DISK_PAGE_SIZE), nil, []common.Filter{
+			{Name: "diskId", Values: diskIds[start:end]},
+		})
 
-			config := meta.(*JDCloudConfig)
-			c := client.NewDiskClient(config.Credential)
-			req := apis.NewDescribeDiskRequest(config.Region, diskId)
+		e := resource.Retry(time.Minute, func() *resource.RetryError {
 
-			resp, err := c.DescribeDisk(req)
+			resp, err := c.DescribeDisks(req)
 
-			if err == nil && resp.Error.Code == REQUEST_COMPLETED && resp.Result.Disk.Charge.ChargeMode != "" {
-				diskState = resp.Result.Disk.Status
-				diskItem = resp.Result.Disk
+			if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+				for _, item := range resp.Result.Disks {
+					disks[item.DiskId] = item
+				}
 				return nil
 			}
 
 			if connectionError(err) {
-				return resource.RetryableError(err)
+				return resource.RetryableError(formatConnectionErrorMessage())
 			} else {
-				return resource.NonRetryableError(err)
+				return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
 			}
-
 		})
-
-		if err != nil {
-			return nil, "", err
+		if e != nil {
+			return nil, e
 		}
-
-		return diskItem, diskState, nil
 	}
+	return disks, nil
 }
 
 // This function will be used here and instance, level 0
@@ -54,7 +81,7 @@ func performDiskCreate(d *schema.ResourceData, meta interface{}, spec *disk.Disk
 
 	config := meta.(*JDCloudConfig)
 	c := client.NewDiskClient(config.Credential)
-	req := apis.NewCreateDisksRequest(config.Region, spec, MAX_DISK_COUNT, diskClientTokenDefault())
+	req := apis.NewCreateDisksRequest(config.Region, spec, MAX_DISK_COUNT, clientTokenDefault())
 
 	e = RetryWithParamsSpecified(2*time.Second, time.Minute, func() *resource.RetryError {
 
@@ -94,19 +121,32 @@ func performDiskDelete(d *schema.ResourceData, meta interface{}, id string) erro
 
 }
 
+// This function will send an extend request, level 0
+// Disks can only grow, it works on both available and attached disks
+func performDiskExtend(meta interface{}, id string, sizeGB int) error {
+
+	config := meta.(*JDCloudConfig)
+	c := client.NewDiskClient(config.Credential)
+	req := apis.NewExtendDiskRequest(config.Region, id, sizeGB)
+
+	return resource.Retry(time.Minute, func() *resource.RetryError {
+		resp, err := c.ExtendDisk(req)
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			return nil
+		}
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+}
+
 // This function will wait until a disk is Available, level 1 -> based on diskStatusRefreshFunc
 // Disk-Creation usually take couple of minutes, let's wait for it :)
-func diskStatusWaiter(d *schema.ResourceData, meta interface{}, id string, pending, target []string) (err error) {
-
-	stateConf := &resource.StateChangeConf{
-		Pending:    pending,
-		Target:     target,
-		Refresh:    diskStatusRefreshFunc(d, meta, id),
-		Delay:      3 * time.Second,
-		Timeout:    2 * time.Minute,
-		MinTimeout: 1 * time.Second,
-	}
-	if _, err = stateConf.WaitForState(); err != nil {
+func diskStatusWaiter(d *schema.ResourceData, meta interface{}, id string, pending, target []string, timeout time.Duration) (err error) {
+
+	if err = waitForRefresh(id, diskStatusRefreshFunc(d, meta, id), pending, target, timeout); err != nil {
 		return fmt.Errorf("[E] Failed in creatingDisk/Waiting disk,err message:%v", err)
 	}
 	return nil
@@ -123,6 +163,12 @@ func resourceJDCloudDisk() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		CustomizeDiff: chargeCustomizeDiff("charge", true),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(DISK_TIMEOUT * time.Second),
+			Delete: schema.DefaultTimeout(DISK_TIMEOUT * time.Second),
+		},
 
 		Schema: map[string]*schema.Schema{
 			"az": {
@@ -156,24 +202,38 @@ func resourceJDCloudDisk() *schema.Resource {
 				Optional: true,
 				ForceNew: true,
 			},
+			// Postpaid_by_usage unavailable in Disk
+			"charge":              chargeSchemaWithLegacy(CHARGE_PREPAID_BY_DURATION, CHARGE_POSTPAID_BY_DURATION),
+			"prepaid_on_destroy":  prepaidOnDestroySchema(),
+			"deletion_protection": deletionProtectionSchema(),
+			"tags": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
 			"charge_duration": {
-				Type:     schema.TypeInt,
-				Optional: true,
-				ForceNew: true,
+				Type:          schema.TypeInt,
+				Optional:      true,
+				ForceNew:      true,
+				Deprecated:    "Use charge.charge_duration instead",
+				ConflictsWith: []string{"charge"},
 			},
 			"charge_mode": {
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
-				// Postpaid_by_usage unavailable in Disk
-				ValidateFunc: validateStringCandidates("prepaid_by_duration", "postpaid_by_duration"),
-				ForceNew:     true,
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ValidateFunc:  validateStringCandidates(CHARGE_PREPAID_BY_DURATION, CHARGE_POSTPAID_BY_DURATION),
+				ForceNew:      true,
+				Deprecated:    "Use charge.charge_mode instead",
+				ConflictsWith: []string{"charge"},
 			},
 			"charge_unit": {
-				Type:         schema.TypeString,
-				Optional:     true,
-				ValidateFunc: validateStringCandidates("month", "year"),
-				ForceNew:     true,
+				Type:          schema.TypeString,
+				Optional:      true,
+				ValidateFunc:  validateStringCandidates(CHARGE_UNIT_MONTH, CHARGE_UNIT_YEAR),
+				ForceNew:      true,
+				Deprecated:    "Use charge.charge_unit instead",
+				ConflictsWith: []string{"charge"},
 			},
 		},
 	}
@@ -191,35 +251,13 @@ func resourceJDCloudDiskCreate(d *schema.ResourceData, meta interface{}) error {
 		diskSpec.SnapshotId = GetStringAddr(d, "snapshot_id")
 	}
 
-	chargeSpec := models.ChargeSpec{}
-	if chargeModeInterface, ok := d.GetOk("charge_mode"); ok {
-
-		chargeModeString := chargeModeInterface.(string)
-		chargeSpec.ChargeMode = &chargeModeString
-
-		if chargeModeString == "prepaid_by_duration" {
-
-			if _, ok := d.GetOk("charge_unit"); ok {
-				chargeSpec.ChargeUnit = GetStringAddr(d, "charge_unit")
-			} else {
-				return fmt.Errorf("[ERROR] Failed in resourceJDCloudDiskCreate, charge_unit invalid")
-			}
-
-			if _, ok := d.GetOk("charge_duration"); ok {
-				chargeSpec.ChargeUnit = GetStringAddr(d, "charge_duration")
-			} else {
-				return fmt.Errorf("[ERROR] Failed in resourceJDCloudDiskCreate, charge_duration invalid")
-			}
-		}
-
-		diskSpec.Charge = &chargeSpec
-	}
+	diskSpec.Charge = chargeSpecWithLegacy(d)
 
 	id, e := performDiskCreate(d, meta, &diskSpec)
 	if e != nil {
 		return e
 	}
-	e = diskStatusWaiter(d, meta, id, []string{DISK_CREATING}, []string{DISK_AVAILABLE})
+	e = diskStatusWaiter(d, meta, id, []string{DISK_CREATING}, []string{DISK_AVAILABLE}, d.Timeout(schema.TimeoutCreate))
 	if e != nil {
 		return e
 	}
@@ -231,6 +269,8 @@ func resourceJDCloudDiskCreate(d *schema.ResourceData, meta interface{}) error {
 
 func resourceJDCloudDiskRead(d *schema.ResourceData, meta interface{}) error {
 
+	readDeletionProtection(d)
+
 	config := meta.(*JDCloudConfig)
 	diskClient := client.NewDiskClient(config.Credential)
 	req := apis.NewDescribeDiskRequestWithAllParams(config.Region, d.Id())
@@ -265,6 +305,8 @@ func resourceJDCloudDiskRead(d *schema.ResourceData, meta interface{}) error {
 		d.Set("description", resp.Result.Disk.Description)
 		d.Set("snapshot_id", resp.Result.Disk.SnapshotId)
 		d.Set("charge_mode", resp.Result.Disk.Charge.ChargeMode)
+		d.Set("charge", chargeToTypeList(resp.Result.Disk.Charge, d.Get("charge").([]interface{})))
+		d.Set("tags", tagsToMap(resp.Result.Disk.Tags))
 		d.Set("disk_size_gb", resp.Result.Disk.DiskSizeGB)
 		return nil
 	})
@@ -301,12 +343,31 @@ func resourceJDCloudDiskUpdate(d *schema.ResourceData, meta interface{}) error {
 
 func resourceJDCloudDiskDelete(d *schema.ResourceData, meta interface{}) error {
 
-	e := performDiskDelete(d, meta, d.Id())
+	if err := verifyDeletionProtection(d); err != nil {
+		return err
+	}
+
+	// Prepaid disks can not be deleted before they expire
+	item, status, e := diskStatusRefreshFunc(d, meta, d.Id())()
+	if e != nil {
+		return e
+	}
+	if status == DISK_DELETED {
+		d.SetId("")
+		return nil
+	}
+	if item != nil {
+		if proceed, err := prepaidDestroyAllowed(d, meta, item.(disk.Disk).Charge.ChargeMode); !proceed {
+			return err
+		}
+	}
+
+	e = performDiskDelete(d, meta, d.Id())
 	if e != nil {
 		return e
 	}
 
-	e = diskStatusWaiter(d, meta, d.Id(), []string{DISK_DELETING}, []string{DISK_DELETED})
+	e = diskStatusWaiter(d, meta, d.Id(), []string{DISK_DELETING}, []string{DISK_DELETED}, d.Timeout(schema.TimeoutDelete))
 	if e != nil {
 		return e
 	}
@@ -14,19 +14,22 @@ import (
 
 //---------------------------------------------------------------------------------	ATTACHMENT-SCHEMA-HELPERS
 
-// This function will return the latest status of a disk level 1 -> Based on QueryInstanceDetail
+// This function will return the latest status of a disk level 1 -> Based on instanceStatusRefreshFunc
 // *We've already had a disk status refresher, this one is generated since by checking the
 // attachment status we are going to describe VMs rather than disk itself
 func diskAttachmentStatusRefreshFunc(d *schema.ResourceData, meta interface{}, instanceId, diskId string) resource.StateRefreshFunc {
 	return func() (diskItem interface{}, diskState string, e error) {
 
-		resp, err := QueryInstanceDetail(d, meta, instanceId)
+		item, _, err := instanceStatusRefreshFunc(d, meta, instanceId)()
 		if err != nil {
 			return nil, "", err
 		}
+		if item == nil {
+			return nil, "DiskNotFound", fmt.Errorf("DiskNotFound")
+		}
 
 		// We've found the expected disk
-		for _, d := range resp.Result.Instance.DataDisks {
+		for _, d := range item.(vm.Instance).DataDisks {
 			if d.CloudDisk.DiskId == diskId {
 				return d, d.Status, nil
 			}
@@ -49,7 +52,7 @@ func diskAttachmentStatusRefreshFunc(d *schema.ResourceData, meta interface{}, i
 																				         -> 400 Disk Already Attached (what...)
 
 */
-func performDiskAttach(meta interface{}, diskID, instanceID, deviceName string, autoDelete bool) (requestId string, e error) {
+func performDiskAttach(meta interface{}, diskID, instanceID, deviceName string, autoDelete bool, timeout time.Duration) (requestId string, e error) {
 
 	stateConf := &resource.StateChangeConf{
 		Pending: []string{"connection_error", "task_conflict"},
@@ -80,6 +83,7 @@ func performDiskAttach(meta interface{}, diskID, instanceID, deviceName string,
 			}
 
 			// -----------------------------------------------  Concurrent attachment error
+			// Attachments on the same instance are serialised by lockParent, tasks started elsewhere still conflict
 
 			log.Printf("[D] Disk Attachemt error happens, error=%v ,resp=%v", err, resp)
 			if resp.Error.Code == REQUEST_INVALID && strings.Contains(resp.Error.Message, DISK_CONCURRENT_ATTACHMENT_ERROR) {
@@ -97,7 +101,7 @@ func performDiskAttach(meta interface{}, diskID, instanceID, deviceName string,
 				fmt.Errorf("Failed in sending disk attachment request, error=%v ,resp=%v", err, resp)
 		},
 		Delay:      3 * time.Second,
-		Timeout:    2 * time.Minute,
+		Timeout:    timeout,
 		MinTimeout: 1 * time.Second,
 	}
 
@@ -108,7 +112,7 @@ func performDiskAttach(meta interface{}, diskID, instanceID, deviceName string,
 }
 
 // This function will send a request of detachment,do not wait,just send, level 0
-func performDiskDetach(meta interface{}, diskID, instanceID string, forceDetach bool) error {
+func performDiskDetach(meta interface{}, diskID, instanceID string, forceDetach bool, timeout time.Duration) error {
 
 	stateConf := &resource.StateChangeConf{
 		Pending: []string{"connection_error", "task_conflict"},
@@ -134,6 +138,7 @@ func performDiskDetach(meta interface{}, diskID, instanceID string, forceDetach
 			}
 
 			// -----------------------------------------------  Concurrent attachment error
+			// Attachments on the same instance are serialised by lockParent, tasks started elsewhere still conflict
 
 			log.Printf("[D] Disk Attachemt error happens, error=%v ,resp=%v", err, resp)
 			if resp.Error.Code == REQUEST_INVALID && strings.Contains(resp.Error.Message, DISK_CONCURRENT_ATTACHMENT_ERROR) {
@@ -151,7 +156,7 @@ func performDiskDetach(meta interface{}, diskID, instanceID string, forceDetach
 				fmt.Errorf("Failed in sending disk attachment request, error=%v ,resp=%v", err, resp)
 		},
 		Delay:      3 * time.Second,
-		Timeout:    2 * time.Minute,
+		Timeout:    timeout,
 		MinTimeout: 1 * time.Second,
 	}
 
@@ -162,18 +167,9 @@ func performDiskDetach(meta interface{}, diskID, instanceID string, forceDetach
 }
 
 // This function will wait until certain status has been reached, level 2 -> based on diskAttachmentStatusRefreshFunc
-func diskAttachmentWaiter(d *schema.ResourceData, meta interface{}, instanceId, diskId string, pending, target []string) (err error) {
+func diskAttachmentWaiter(d *schema.ResourceData, meta interface{}, instanceId, diskId string, pending, target []string, timeout time.Duration) (err error) {
 
-	stateConf := &resource.StateChangeConf{
-		Pending:    pending,
-		Target:     target,
-		Refresh:    diskAttachmentStatusRefreshFunc(d, meta, instanceId, diskId),
-		Delay:      3 * time.Second,
-		Timeout:    2 * time.Minute,
-		MinTimeout: 1 * time.Second,
-	}
-
-	if _, err = stateConf.WaitForState(); err != nil {
+	if err = waitForRefresh(diskId, diskAttachmentStatusRefreshFunc(d, meta, instanceId, diskId), pending, target, timeout); err != nil {
 		return fmt.Errorf("[E] Failed in AttachingDisk/WaitingDiskAttaching ,err message:%v", err)
 	}
 	return nil
@@ -188,6 +184,25 @@ func resourceJDCloudDiskAttachment() *schema.Resource {
 		Read:   resourceJDCloudDiskAttachmentRead,
 		Update: resourceJDCloudDiskAttachmentUpdate,
 		Delete: resourceJDCloudDiskAttachmentDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceJDCloudDiskAttachmentImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(DISK_ATTACHMENT_TIMEOUT * time.Second),
+			Delete: schema.DefaultTimeout(DISK_ATTACHMENT_TIMEOUT * time.Second),
+		},
+
+		// Version 0 identifies an attachment by the request ID
+		// Version 1 identifies an attachment by "<instance_id>:<disk_id>"
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceJDCloudDiskAttachmentV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: compositeIdStateUpgradeFunc("instance_id", "disk_id"),
+			},
+		},
 
 		Schema: map[string]*schema.Schema{
 			"instance_id": &schema.Schema{
@@ -227,6 +242,9 @@ func resourceJDCloudDiskAttachment() *schema.Resource {
 
 func resourceJDCloudDiskAttachmentCreate(d *schema.ResourceData, meta interface{}) error {
 
+	lockParent(meta, d.Get("instance_id").(string))
+	defer unlockParent(meta, d.Get("instance_id").(string))
+
 	instanceID := d.Get("instance_id").(string)
 	diskID := d.Get("disk_id").(string)
 	deviceName := ""
@@ -238,17 +256,17 @@ func resourceJDCloudDiskAttachmentCreate(d *schema.ResourceData, meta interface{
 		autoDelete = d.Get("auto_delete").(bool)
 	}
 
-	id, e := performDiskAttach(meta, diskID, instanceID, deviceName, autoDelete)
+	_, e := performDiskAttach(meta, diskID, instanceID, deviceName, autoDelete, d.Timeout(schema.TimeoutCreate))
 	if e != nil {
 		return e
 	}
 
-	e = diskAttachmentWaiter(d, meta, instanceID, diskID, []string{DISK_ATTACHING}, []string{DISK_ATTACHED})
+	e = diskAttachmentWaiter(d, meta, instanceID, diskID, []string{DISK_ATTACHING}, []string{DISK_ATTACHED}, d.Timeout(schema.TimeoutCreate))
 	if e != nil {
 		return e
 	}
 
-	d.SetId(id)
+	d.SetId(buildCompositeId(instanceID, diskID))
 	return resourceJDCloudDiskAttachmentRead(d, meta)
 }
 
@@ -260,6 +278,13 @@ func resourceJDCloudDiskAttachmentRead(d *schema.ResourceData, meta interface{})
 	f := diskAttachmentStatusRefreshFunc(d, meta, instanceID, diskID)
 	disk, status, err := f()
 
+	// Detached out of band, or the instance has gone
+	if status == "DiskNotFound" {
+		log.Printf("[WARN] Disk %s is no longer attached to %s, removed locally", diskID, instanceID)
+		d.SetId("")
+		return nil
+	}
+
 	if err != nil {
 		return err
 	}
@@ -279,6 +304,9 @@ func resourceJDCloudDiskAttachmentRead(d *schema.ResourceData, meta interface{})
 
 func resourceJDCloudDiskAttachmentUpdate(d *schema.ResourceData, meta interface{}) error {
 
+	lockParent(meta, d.Get("instance_id").(string))
+	defer unlockParent(meta, d.Get("instance_id").(string))
+
 	if d.HasChange("auto_delete") {
 
 		config := meta.(*JDCloudConfig)
@@ -304,20 +332,35 @@ func resourceJDCloudDiskAttachmentUpdate(d *schema.ResourceData, meta interface{
 	return nil
 }
 
+func resourceJDCloudDiskAttachmentImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+
+	parts, err := parseCompositeId(d.Id(), 2, "<instance_id>:<disk_id>")
+	if err != nil {
+		return nil, err
+	}
+
+	d.Set("instance_id", parts[0])
+	d.Set("disk_id", parts[1])
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceJDCloudDiskAttachmentDelete(d *schema.ResourceData, meta interface{}) error {
 
+	lockParent(meta, d.Get("instance_id").(string))
+	defer unlockParent(meta, d.Get("instance_id").(string))
+
 	instanceID := d.Get("instance_id").(string)
 	diskID := d.Get("disk_id").(string)
 	force_detach := false
 	if _, ok := d.GetOk("force_detach"); ok {
 		force_detach = d.Get("force_detach").(bool)
 	}
-	e := performDiskDetach(meta, diskID, instanceID, force_detach)
+	e := performDiskDetach(meta, diskID, instanceID, force_detach, d.Timeout(schema.TimeoutDelete))
 	if e != nil {
 		return e
 	}
 
-	e = diskAttachmentWaiter(d, meta, instanceID, diskID, []string{DISK_ATTACHED, DISK_DETACHING}, []string{DISK_DETACHED})
+	e = diskAttachmentWaiter(d, meta, instanceID, diskID, []string{DISK_ATTACHED, DISK_DETACHING}, []string{DISK_DETACHED}, d.Timeout(schema.TimeoutDelete))
 	if e != nil {
 		return e
 	}
@@ -325,3 +368,37 @@ func resourceJDCloudDiskAttachmentDelete(d *schema.ResourceData, meta interface{
 	d.SetId("")
 	return nil
 }
+
+func resourceJDCloudDiskAttachmentV0() *schema.Resource {
+
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"instance_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"disk_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"auto_delete": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"device_name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"force_detach": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				ForceNew: true,
+			},
+		},
+	}
+}
@@ -85,6 +85,12 @@ func TestAccJDCloudDiskAttachment_basic(t *testing.T) {
 						"jdcloud_disk_attachment.terraform_da", "force_detach"),
 				),
 			},
+			{
+				ResourceName:            "jdcloud_disk_attachment.terraform_da",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"force_detach"},
+			},
 		},
 	})
 }
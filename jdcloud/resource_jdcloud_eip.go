@@ -4,6 +4,7 @@ import (
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/charge/models"
+	common "github.com/jdcloud-api/jdcloud-sdk-go/services/common/models"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/vpc/apis"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/vpc/client"
 	vpcModels "github.com/jdcloud-api/jdcloud-sdk-go/services/vpc/models"
@@ -14,10 +15,12 @@ func resourceJDCloudEIP() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceJDCloudEIPCreate,
 		Read:   resourceJDCloudEIPRead,
+		Update: resourceJDCloudEIPUpdate,
 		Delete: resourceJDCloudEIPDelete,
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		CustomizeDiff: chargeCustomizeDiff("charge", false),
 
 		Schema: map[string]*schema.Schema{
 			"eip_provider": &schema.Schema{
@@ -36,6 +39,8 @@ func resourceJDCloudEIP() *schema.Resource {
 				Computed: true,
 				ForceNew: true,
 			},
+			"charge":              chargeSchema(CHARGE_PREPAID_BY_DURATION, CHARGE_POSTPAID_BY_USAGE, CHARGE_POSTPAID_BY_DURATION),
+			"deletion_protection": deletionProtectionSchema(),
 		},
 	}
 }
@@ -48,37 +53,91 @@ func resourceJDCloudEIPCreate(d *schema.ResourceData, meta interface{}) error {
 		Provider:      d.Get("eip_provider").(string),
 		ChargeSpec:    &models.ChargeSpec{},
 	}
+	if chargeSpec := typeListToChargeSpec(d.Get("charge").([]interface{})); chargeSpec != nil {
+		elasticIpSpec.ChargeSpec = chargeSpec
+	}
 	vpcClient := client.NewVpcClient(config.Credential)
 	req := apis.NewCreateElasticIpsRequest(config.Region, MAX_EIP_COUNT, &elasticIpSpec)
 	if _, ok := d.GetOk("elastic_ip_address"); ok {
 		req.ElasticIpAddress = GetStringAddr(d, "elastic_ip_address")
 	}
 
-	err := resource.Retry(20*time.Second, func() *resource.RetryError {
+	create := func() (string, *resource.RetryError) {
 
 		resp, err := vpcClient.CreateElasticIps(req)
 
 		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
-			d.SetId(resp.Result.ElasticIpIds[0])
-			return nil
+			return resp.Result.ElasticIpIds[0], nil
 		}
 
 		if connectionError(err) {
-			return resource.RetryableError(formatConnectionErrorMessage())
+			return "", resource.RetryableError(formatConnectionErrorMessage())
 		} else {
-			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+			return "", resource.NonRetryableError(formatErrorMessage(resp.Error, err))
 		}
-	})
+	}
+
+	// CreateElasticIps accepts no client token. Elastic IPs existing beforehand are recorded, after a lost response
+	// the new one is looked up by address if specified, or by provider and bandwidth among unassociated ones
+	lookup := func() ([]string, error) {
+		return queryElasticIpIds(meta, d.Get("elastic_ip_address").(string), elasticIpSpec.Provider, elasticIpSpec.BandwidthMbps)
+	}
+	id, err := createWithLookup(20*time.Second, lookup, create)
 
 	if err != nil {
 		return err
 	}
+	d.SetId(id)
 
 	return resourceJDCloudEIPRead(d, meta)
 }
 
+// Query ids of unassociated elastic IPs with the given provider and bandwidth, filtered by address if specified
+// Without an address all elastic IPs of the region are paged through
+func queryElasticIpIds(meta interface{}, address, provider string, bandwidth int) (ids []string, e error) {
+
+	config := meta.(*JDCloudConfig)
+	vpcClient := client.NewVpcClient(config.Credential)
+	filters := []common.Filter{}
+	if address != "" {
+		filters = append(filters, common.Filter{Name: "elasticIpAddress", Values: []string{address}})
+	}
+
+	ids = []string{}
+	for page, total := 1, 1; (page-1)*MAX_EIP_PAGE_SIZE < total; page++ {
+
+		req := apis.NewDescribeElasticIpsRequestWithAllParams(config.Region, intAddr(page), intAddr(MAX_EIP_PAGE_SIZE), filters)
+		e = resource.Retry(time.Minute, func() *resource.RetryError {
+
+			resp, err := vpcClient.DescribeElasticIps(req)
+
+			if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+				for _, eip := range resp.Result.ElasticIps {
+					if eip.Provider == provider && eip.BandwidthMbps == bandwidth && eip.InstanceId == "" {
+						ids = append(ids, eip.ElasticIpId)
+					}
+				}
+				total = resp.Result.TotalCount
+				return nil
+			}
+
+			if connectionError(err) {
+				return resource.RetryableError(formatConnectionErrorMessage())
+			} else {
+				return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+			}
+		})
+		if e != nil {
+			return nil, e
+		}
+	}
+	return ids, nil
+}
+
 func resourceJDCloudEIPRead(d *schema.ResourceData, meta interface{}) error {
 
+	readDeletionProtection(d)
+
 	config := meta.(*JDCloudConfig)
 	req := apis.NewDescribeElasticIpRequest(config.Region, d.Id())
 	vpcClient := client.NewVpcClient(config.Credential)
@@ -90,6 +149,7 @@ func resourceJDCloudEIPRead(d *schema.ResourceData, meta interface{}) error {
 			d.Set("elastic_ip_address", resp.Result.ElasticIp.ElasticIpAddress)
 			d.Set("bandwidth_mbps", resp.Result.ElasticIp.BandwidthMbps)
 			d.Set("eip_provider", resp.Result.ElasticIp.Provider)
+			d.Set("charge", chargeToTypeList(resp.Result.ElasticIp.Charge, d.Get("charge").([]interface{})))
 
 			return nil
 		}
@@ -106,8 +166,17 @@ func resourceJDCloudEIPRead(d *schema.ResourceData, meta interface{}) error {
 	})
 }
 
+// Only deletion_protection can be modified, which lives in the state
+func resourceJDCloudEIPUpdate(d *schema.ResourceData, meta interface{}) error {
+	return resourceJDCloudEIPRead(d, meta)
+}
+
 func resourceJDCloudEIPDelete(d *schema.ResourceData, meta interface{}) error {
 
+	if err := verifyDeletionProtection(d); err != nil {
+		return err
+	}
+
 	config := meta.(*JDCloudConfig)
 	elasticIpId := d.Id()
 	rq := apis.NewDeleteElasticIpRequest(config.Region, elasticIpId)
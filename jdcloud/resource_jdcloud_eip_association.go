@@ -16,6 +16,25 @@ func resourceJDCloudAssociateElasticIp() *schema.Resource {
 		Create: resourceAssociateElasticIpCreate,
 		Read:   resourceAssociateElasticIpRead,
 		Delete: resourceAssociateElasticIpDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceAssociateElasticIpImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(3 * time.Minute),
+			Delete: schema.DefaultTimeout(3 * time.Minute),
+		},
+
+		// Version 0 identifies an association by the request ID
+		// Version 1 identifies an association by "<instance_id>:<elastic_ip_id>"
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceJDCloudAssociateElasticIpV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: compositeIdStateUpgradeFunc("instance_id", "elastic_ip_id"),
+			},
+		},
 
 		Schema: map[string]*schema.Schema{
 			"instance_id": &schema.Schema{
@@ -36,23 +55,26 @@ func resourceJDCloudAssociateElasticIp() *schema.Resource {
 
 func resourceAssociateElasticIpCreate(d *schema.ResourceData, meta interface{}) error {
 
+	lockParent(meta, d.Get("instance_id").(string))
+	defer unlockParent(meta, d.Get("instance_id").(string))
+
 	config := meta.(*JDCloudConfig)
 	instanceID := d.Get("instance_id").(string)
 	elasticIpId := d.Get("elastic_ip_id").(string)
 
 	vmClient := client.NewVmClient(config.Credential)
 	rq := apis.NewAssociateElasticIpRequest(config.Region, instanceID, elasticIpId)
-	err := resource.Retry(3*time.Minute, func() *resource.RetryError {
+	err := resource.Retry(d.Timeout(schema.TimeoutCreate), func() *resource.RetryError {
 
 		resp, err := vmClient.AssociateElasticIp(rq)
 
 		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
-			d.SetId(resp.RequestID)
+			d.SetId(buildCompositeId(instanceID, elasticIpId))
 			return nil
 		}
 
 		if resp != nil && resp.Error.Code == REQUEST_INVALID && resp.Error.Status == "FAILED_PRECONDITION" {
-			d.SetId(resp.RequestID)
+			d.SetId(buildCompositeId(instanceID, elasticIpId))
 			return nil
 		}
 
@@ -80,14 +102,22 @@ func resourceAssociateElasticIpRead(d *schema.ResourceData, meta interface{}) er
 	return resource.Retry(3*time.Minute, func() *resource.RetryError {
 		resp, err := c.DescribeElasticIp(req)
 		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+
+			// Disassociated or associated with another instance out of band
+			if resp.Result.ElasticIp.InstanceId != instanceID {
+				log.Printf("[WARN] EIP=%s removed locally", resp.Result.ElasticIp.ElasticIpAddress)
+				d.SetId("")
+				return nil
+			}
+
 			d.Set("elastic_ip_id", resp.Result.ElasticIp.ElasticIpId)
 			d.Set("instance_id", resp.Result.ElasticIp.InstanceId)
 			return nil
 		}
 
-		if resp.Result.ElasticIp.InstanceId != instanceID {
-			log.Printf("[WARN] EIP=%s removed locally", resp.Result.ElasticIp.ElasticIpAddress)
+		if resp != nil && resp.Error.Code == RESOURCE_NOT_FOUND {
 			d.SetId("")
+			return nil
 		}
 
 		if connectionError(err) {
@@ -98,15 +128,30 @@ func resourceAssociateElasticIpRead(d *schema.ResourceData, meta interface{}) er
 	})
 }
 
+func resourceAssociateElasticIpImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+
+	parts, err := parseCompositeId(d.Id(), 2, "<instance_id>:<elastic_ip_id>")
+	if err != nil {
+		return nil, err
+	}
+
+	d.Set("instance_id", parts[0])
+	d.Set("elastic_ip_id", parts[1])
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceAssociateElasticIpDelete(d *schema.ResourceData, meta interface{}) error {
 
+	lockParent(meta, d.Get("instance_id").(string))
+	defer unlockParent(meta, d.Get("instance_id").(string))
+
 	config := meta.(*JDCloudConfig)
 	instanceID := d.Get("instance_id").(string)
 	elasticIpId := d.Get("elastic_ip_id").(string)
 	rq := apis.NewDisassociateElasticIpRequest(config.Region, instanceID, elasticIpId)
 	vmClient := client.NewVmClient(config.Credential)
 
-	return resource.Retry(3*time.Minute, func() *resource.RetryError {
+	return resource.Retry(d.Timeout(schema.TimeoutDelete), func() *resource.RetryError {
 
 		resp, err := vmClient.DisassociateElasticIp(rq)
 
@@ -122,3 +167,20 @@ func resourceAssociateElasticIpDelete(d *schema.ResourceData, meta interface{})
 		}
 	})
 }
+
+func resourceJDCloudAssociateElasticIpV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"instance_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"elastic_ip_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+		},
+	}
+}
@@ -44,6 +44,11 @@ func TestAccJDCloudEIPAssociation_basic(t *testing.T) {
 						"jdcloud_eip_association.terraform-eip-association", "instance_id", packer_instance),
 				),
 			},
+			{
+				ResourceName:      "jdcloud_eip_association.terraform-eip-association",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 		},
 	})
 }
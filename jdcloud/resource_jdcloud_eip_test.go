@@ -6,6 +6,7 @@ import (
 	"github.com/hashicorp/terraform/terraform"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/vpc/apis"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/vpc/client"
+	"regexp"
 	"strconv"
 	"testing"
 	"time"
@@ -19,6 +20,9 @@ const TestAccEIPConfig = `
 resource "jdcloud_eip" "eip-terraform"{
 	eip_provider = "bgp" 
 	bandwidth_mbps = 10
+	charge {
+		charge_mode = "postpaid_by_usage"
+	}
 }
 `
 
@@ -39,6 +43,8 @@ func TestAccJDCloudEIP_basic(t *testing.T) {
 						"jdcloud_eip.eip-terraform", "eip_provider", "bgp"),
 					resource.TestCheckResourceAttr(
 						"jdcloud_eip.eip-terraform", "bandwidth_mbps", "10"),
+					resource.TestCheckResourceAttr(
+						"jdcloud_eip.eip-terraform", "charge.0.charge_mode", "postpaid_by_usage"),
 
 					// After resource_XYZ_Read these value will be set.
 					resource.TestCheckResourceAttrSet(
@@ -54,6 +60,47 @@ func TestAccJDCloudEIP_basic(t *testing.T) {
 	})
 }
 
+const TestAccEIPProtectionTemplate = `
+resource "jdcloud_eip" "eip-terraform"{
+	eip_provider = "bgp" 
+	bandwidth_mbps = 10
+	deletion_protection = %t
+}
+`
+
+// Destroying a protected EIP fails, it can be destroyed once protection is turned off
+func TestAccJDCloudEIP_deletionProtection(t *testing.T) {
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccEIPDestroy("jdcloud_eip.eip-terraform"),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(TestAccEIPProtectionTemplate, true),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfEIPExists("jdcloud_eip.eip-terraform"),
+					resource.TestCheckResourceAttr(
+						"jdcloud_eip.eip-terraform", "deletion_protection", "true"),
+				),
+			},
+			{
+				Config:      fmt.Sprintf(TestAccEIPProtectionTemplate, true),
+				Destroy:     true,
+				ExpectError: regexp.MustCompile("deletion_protection enabled"),
+			},
+			{
+				Config: fmt.Sprintf(TestAccEIPProtectionTemplate, false),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfEIPExists("jdcloud_eip.eip-terraform"),
+					resource.TestCheckResourceAttr(
+						"jdcloud_eip.eip-terraform", "deletion_protection", "false"),
+				),
+			},
+		},
+	})
+}
+
 func testAccIfEIPExists(resourceName string) resource.TestCheckFunc {
 
 	return func(stateInfo *terraform.State) error {
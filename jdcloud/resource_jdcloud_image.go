@@ -0,0 +1,380 @@
+package jdcloud
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	diskApis "github.com/jdcloud-api/jdcloud-sdk-go/services/disk/apis"
+	diskClient "github.com/jdcloud-api/jdcloud-sdk-go/services/disk/client"
+	"github.com/jdcloud-api/jdcloud-sdk-go/services/vm/apis"
+	"github.com/jdcloud-api/jdcloud-sdk-go/services/vm/client"
+	vm "github.com/jdcloud-api/jdcloud-sdk-go/services/vm/models"
+	"log"
+	"time"
+)
+
+/*
+	Images of cloud disk instances are backed by snapshots of these disks,
+	they are not removed together with the image by JDCloud, hence deleted explicitly on destroy
+*/
+
+//----------------------------------------------------------------------------------- OTHERS
+
+// Level 0 -> Query an image in the given region, nil is returned if it does not exist
+func queryImage(m interface{}, region, imageId string) (image *vm.Image, e error) {
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	req := apis.NewDescribeImageRequest(region, imageId)
+
+	e = resource.Retry(time.Minute, func() *resource.RetryError {
+
+		resp, err := vmClient.DescribeImage(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			image = &resp.Result.Image
+			return nil
+		}
+		if err == nil && resp.Error.Code == RESOURCE_NOT_FOUND {
+			return nil
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+	return
+}
+
+// Level 0 -> Ids of private images with the given name
+func queryPrivateImageIdsByName(m interface{}, name string) (ids []string, e error) {
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	ids = []string{}
+
+	for page, total := 1, 1; (page-1)*MAX_VM_PAGE_SIZE < total; page++ {
+
+		req := apis.NewDescribeImagesRequestWithAllParams(config.Region, stringAddr(IMAGE_PRIVATE), nil, nil, nil, nil, intAddr(page), intAddr(MAX_VM_PAGE_SIZE))
+		e = resource.Retry(time.Minute, func() *resource.RetryError {
+
+			resp, err := vmClient.DescribeImages(req)
+
+			if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+				for _, image := range resp.Result.Images {
+					if image.Name == name {
+						ids = append(ids, image.ImageId)
+					}
+				}
+				total = resp.Result.TotalCount
+				return nil
+			}
+
+			if connectionError(err) {
+				return resource.RetryableError(formatConnectionErrorMessage())
+			} else {
+				return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+			}
+		})
+		if e != nil {
+			return nil, e
+		}
+	}
+	return ids, nil
+}
+
+// Used to refresh image status level 0 -> Based on queryImage
+func imageStatusRefreshFunc(meta interface{}, region, imageId string) resource.StateRefreshFunc {
+
+	return func() (interface{}, string, error) {
+
+		image, err := queryImage(meta, region, imageId)
+		if err != nil {
+			return nil, "", err
+		}
+		if image == nil {
+			return imageId, IMAGE_DELETED, nil
+		}
+		return image, image.Status, nil
+	}
+}
+
+// Used to refresh until image reached expected status level 1 -> Based on imageStatusRefreshFunc
+func imageStatusWaiter(meta interface{}, region, id string, pending, target []string, timeout time.Duration) (err error) {
+
+	stateConf := &resource.StateChangeConf{
+		Pending:      pending,
+		Target:       target,
+		Refresh:      imageStatusRefreshFunc(meta, region, id),
+		Delay:        3 * time.Second,
+		Timeout:      timeout,
+		PollInterval: meta.(*JDCloudConfig).PollInterval,
+	}
+	if _, err = stateConf.WaitForState(); err != nil {
+		return fmt.Errorf("[E] Failed in imageStatusWaiter/Waiting %s to reach %v, err message:%v", id, target, err)
+	}
+	return nil
+}
+
+// Level 0 -> Snapshots backing the system disk and data disks of an image
+func imageSnapshotIds(image *vm.Image) []string {
+
+	ids := []string{}
+	if image.SnapshotId != "" {
+		ids = append(ids, image.SnapshotId)
+	}
+	for _, disk := range image.DataDisks {
+		if disk.CloudDisk.SnapshotId != "" {
+			ids = append(ids, disk.CloudDisk.SnapshotId)
+		}
+	}
+	return ids
+}
+
+// Level 2 -> Delete an image in the given region, and wait until it is gone
+func deleteImage(m interface{}, region, imageId string, timeout time.Duration) error {
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	req := apis.NewDeleteImageRequest(region, imageId)
+
+	err := resource.Retry(timeout, func() *resource.RetryError {
+
+		resp, err := vmClient.DeleteImage(req)
+
+		if err == nil && (resp.Error.Code == REQUEST_COMPLETED || resp.Error.Code == RESOURCE_NOT_FOUND) {
+			return nil
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return imageStatusWaiter(m, region, imageId, []string{IMAGE_READY, IMAGE_DELETING}, []string{IMAGE_DELETED}, timeout)
+}
+
+// Level 3 -> Delete an image in the given region together with its snapshots
+// Snapshots are looked up again rather than taken from state
+func deleteImageWithSnapshots(m interface{}, region, imageId string, timeout time.Duration) error {
+
+	image, err := queryImage(m, region, imageId)
+	if err != nil || image == nil {
+		return err
+	}
+	snapshotIds := imageSnapshotIds(image)
+
+	if err := deleteImage(m, region, imageId, timeout); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Deleting snapshots %v backing image %s", snapshotIds, imageId)
+	return deleteSnapshots(m, region, snapshotIds)
+}
+
+// Level 0 -> Delete some snapshots, those already deleted are skipped
+func deleteSnapshots(m interface{}, region string, snapshotIds []string) error {
+
+	config := m.(*JDCloudConfig)
+	c := diskClient.NewDiskClient(config.Credential)
+
+	for _, id := range snapshotIds {
+
+		req := diskApis.NewDeleteSnapshotRequest(region, id)
+		err := resource.Retry(time.Minute, func() *resource.RetryError {
+
+			resp, err := c.DeleteSnapshot(req)
+
+			if err == nil && (resp.Error.Code == REQUEST_COMPLETED || resp.Error.Code == RESOURCE_NOT_FOUND) {
+				return nil
+			}
+
+			if connectionError(err) {
+				return resource.RetryableError(formatConnectionErrorMessage())
+			} else {
+				return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Level 0 -> The source instance or image can not be read back from an image,
+// It is missing on imported images, which should not lead to a replacement
+func imageSourceDiffSuppress(k, old, new string, d *schema.ResourceData) bool {
+	return d.Id() != "" && old == ""
+}
+
+//----------------------------------------------------------------------------------- RESOURCE
+
+func resourceJDCloudImage() *schema.Resource {
+
+	return &schema.Resource{
+		Create: resourceJDCloudImageCreate,
+		Read:   resourceJDCloudImageRead,
+		Update: resourceJDCloudImageUpdate,
+		Delete: resourceJDCloudImageDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(IMAGE_TIMEOUT * time.Second),
+			Delete: schema.DefaultTimeout(IMAGE_TIMEOUT * time.Second),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"instance_id": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: imageSourceDiffSuppress,
+			},
+			"image_name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			// Disks besides those attached to the instance, such as snapshots. Only specified on creation
+			"data_disk": {
+				Type:     schema.TypeList,
+				Optional: true,
+				ForceNew: true,
+				Elem:     instanceDiskSchema(true),
+			},
+			"platform": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"os_type": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"system_disk_size_gb": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"snapshot_ids": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func resourceJDCloudImageCreate(d *schema.ResourceData, m interface{}) error {
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	vmClient.SetLogger(vmLogger{})
+
+	req := apis.NewCreateImageRequest(config.Region, d.Get("instance_id").(string), d.Get("image_name").(string), d.Get("description").(string))
+	if _, ok := d.GetOk("data_disk"); ok {
+		req.DataDisks = diskListTypeCloud(typeListToDiskList(d.Get("data_disk").([]interface{})))
+	}
+
+	// CreateImage accepts no client token, after a lost response the image is looked up by name
+	lookup := func() ([]string, error) {
+		return queryPrivateImageIdsByName(m, d.Get("image_name").(string))
+	}
+	imageId, err := createWithLookup(d.Timeout(schema.TimeoutCreate), lookup, func() (string, *resource.RetryError) {
+
+		resp, err := vmClient.CreateImage(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			return resp.Result.ImageId, nil
+		}
+
+		if connectionError(err) {
+			return "", resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return "", resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	d.SetId(imageId)
+
+	if err := imageStatusWaiter(m, config.Region, d.Id(), []string{IMAGE_PENDING}, []string{IMAGE_READY}, d.Timeout(schema.TimeoutCreate)); err != nil {
+		return err
+	}
+	return resourceJDCloudImageRead(d, m)
+}
+
+func resourceJDCloudImageRead(d *schema.ResourceData, m interface{}) error {
+
+	config := m.(*JDCloudConfig)
+	image, err := queryImage(m, config.Region, d.Id())
+	if err != nil {
+		return err
+	}
+	if image == nil {
+		d.SetId("")
+		return nil
+	}
+
+	d.Set("image_name", image.Name)
+	d.Set("description", image.Desc)
+	d.Set("platform", image.Platform)
+	d.Set("os_type", image.OsType)
+	d.Set("system_disk_size_gb", image.SystemDiskSizeGB)
+	if err := d.Set("snapshot_ids", imageSnapshotIds(image)); err != nil {
+		return fmt.Errorf("[ERROR] Failed in setting snapshot_ids, reasons:%s", err.Error())
+	}
+	return nil
+}
+
+func resourceJDCloudImageUpdate(d *schema.ResourceData, m interface{}) error {
+
+	if d.HasChange("image_name") || d.HasChange("description") {
+
+		config := m.(*JDCloudConfig)
+		vmClient := client.NewVmClient(config.Credential)
+		req := apis.NewModifyImageAttributeRequestWithAllParams(config.Region, d.Id(), stringAddr(d.Get("image_name")), stringAddr(d.Get("description")))
+
+		err := resource.Retry(2*time.Minute, func() *resource.RetryError {
+
+			resp, err := vmClient.ModifyImageAttribute(req)
+
+			if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+				return nil
+			}
+
+			if connectionError(err) {
+				return resource.RetryableError(formatConnectionErrorMessage())
+			} else {
+				return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return resourceJDCloudImageRead(d, m)
+}
+
+func resourceJDCloudImageDelete(d *schema.ResourceData, m interface{}) error {
+
+	config := m.(*JDCloudConfig)
+	if err := deleteImageWithSnapshots(m, config.Region, d.Id(), d.Timeout(schema.TimeoutDelete)); err != nil {
+		return err
+	}
+
+	d.SetId("")
+	return nil
+}
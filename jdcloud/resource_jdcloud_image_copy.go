@@ -0,0 +1,128 @@
+package jdcloud
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/jdcloud-api/jdcloud-sdk-go/services/vm/apis"
+	"github.com/jdcloud-api/jdcloud-sdk-go/services/vm/client"
+	"time"
+)
+
+/*
+	The copy lives in destination_region rather than the region of this provider.
+	Clients of this SDK are not bound to any region, requests simply carry destination_region.
+	Hence copies are imported by <destination_region>:<image_id>
+*/
+
+func resourceJDCloudImageCopy() *schema.Resource {
+
+	return &schema.Resource{
+		Create: resourceJDCloudImageCopyCreate,
+		Read:   resourceJDCloudImageCopyRead,
+		Delete: resourceJDCloudImageCopyDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceJDCloudImageCopyImport,
+		},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(IMAGE_TIMEOUT * time.Second),
+			Delete: schema.DefaultTimeout(IMAGE_TIMEOUT * time.Second),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"source_image_id": {
+				Type:             schema.TypeString,
+				Required:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: imageSourceDiffSuppress,
+			},
+			"destination_region": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateRegion(),
+			},
+			"destination_image_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"image_name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func resourceJDCloudImageCopyCreate(d *schema.ResourceData, m interface{}) error {
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	vmClient.SetLogger(vmLogger{})
+
+	region := d.Get("destination_region").(string)
+	if region == config.Region {
+		return fmt.Errorf("[ERROR] destination_region of an image copy can not be the region of this provider, %s", region)
+	}
+	req := apis.NewCopyImagesRequest(config.Region, []string{d.Get("source_image_id").(string)}, region)
+
+	// CopyImages accepts no client token and copies can not be told apart from others in destination_region,
+	// hence it is sent only once. A lost response fails instead of leaving an untracked copy behind
+	resp, err := vmClient.CopyImages(req)
+	if connectionError(err) {
+		return fmt.Errorf("[ERROR] Lost the response of CopyImages, a copy may have been created in %s anyway. Check it in the console, import it by terraform import jdcloud_image_copy.<name> %s:<image_id> or delete it before retrying. Reasons:%s", region, region, err)
+	}
+	if err != nil || resp.Error.Code != REQUEST_COMPLETED {
+		return formatErrorMessage(resp.Error, err)
+	}
+	if len(resp.Result.CopyImages) == 0 {
+		return fmt.Errorf("[ERROR] CopyImages of %s into %s completed without reporting any copy, check %s in the console", d.Get("source_image_id").(string), region, region)
+	}
+	d.SetId(resp.Result.CopyImages[0].DestinationImageId)
+
+	if err := imageStatusWaiter(m, region, d.Id(), []string{IMAGE_PENDING, IMAGE_COPYING}, []string{IMAGE_READY}, d.Timeout(schema.TimeoutCreate)); err != nil {
+		return err
+	}
+	return resourceJDCloudImageCopyRead(d, m)
+}
+
+func resourceJDCloudImageCopyRead(d *schema.ResourceData, m interface{}) error {
+
+	image, err := queryImage(m, d.Get("destination_region").(string), d.Id())
+	if err != nil {
+		return err
+	}
+	if image == nil {
+		d.SetId("")
+		return nil
+	}
+
+	d.Set("destination_image_id", image.ImageId)
+	d.Set("image_name", image.Name)
+	return nil
+}
+
+func resourceJDCloudImageCopyImport(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+
+	parts, err := parseCompositeId(d.Id(), 2, "<destination_region>:<image_id>")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := regionCn[parts[0]]; !ok {
+		return nil, fmt.Errorf("[ERROR] Invalid destination_region '%s'", parts[0])
+	}
+
+	d.Set("destination_region", parts[0])
+	d.SetId(parts[1])
+	return []*schema.ResourceData{d}, nil
+}
+
+func resourceJDCloudImageCopyDelete(d *schema.ResourceData, m interface{}) error {
+
+	if err := deleteImageWithSnapshots(m, d.Get("destination_region").(string), d.Id(), d.Timeout(schema.TimeoutDelete)); err != nil {
+		return err
+	}
+
+	d.SetId("")
+	return nil
+}
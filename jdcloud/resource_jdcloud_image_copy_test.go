@@ -0,0 +1,103 @@
+package jdcloud
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"testing"
+)
+
+/*
+	TestCase : 1. Image an instance, then copy the image into another region and import the copy
+*/
+
+const testAccImageCopyRegion = "cn-east-2"
+
+const testAccImageCopyTemplate = `
+resource "jdcloud_image" "image" {
+  instance_id = "%s"
+  image_name  = "%s"
+}
+
+resource "jdcloud_image_copy" "copy" {
+  source_image_id    = "${jdcloud_image.image.id}"
+  destination_region = "%s"
+}
+`
+
+func generateImageCopyConfig(name string) string {
+	return fmt.Sprintf(testAccImageCopyTemplate, packer_instance, name, testAccImageCopyRegion)
+}
+
+func TestAccJDCloudImageCopy_basic(t *testing.T) {
+
+	var imageId string
+
+	resource.Test(t, resource.TestCase{
+
+		IDRefreshName: "jdcloud_image_copy.copy",
+		PreCheck:      func() { testAccPreCheck(t) },
+		Providers:     testAccProviders,
+		CheckDestroy:  testAccIfImageCopyDestroyed(&imageId),
+		Steps: []resource.TestStep{
+			{
+				Config: generateImageCopyConfig(randomStringWithLength(10)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfImageCopyExists("jdcloud_image_copy.copy", &imageId),
+					resource.TestCheckResourceAttr("jdcloud_image_copy.copy", "destination_region", testAccImageCopyRegion),
+					resource.TestCheckResourceAttrSet("jdcloud_image_copy.copy", "destination_image_id"),
+				),
+			},
+			{
+				ResourceName:            "jdcloud_image_copy.copy",
+				ImportState:             true,
+				ImportStateIdPrefix:     testAccImageCopyRegion + COMPOSITE_ID_SEPARATOR,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"source_image_id"},
+			},
+		},
+	})
+}
+
+func testAccIfImageCopyExists(name string, imageId *string) resource.TestCheckFunc {
+
+	return func(stateInfo *terraform.State) error {
+
+		info, ok := stateInfo.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("[ERROR] testAccIfImageCopyExists failed, resource %s not found in terraform.State", name)
+		}
+		if info.Primary.ID == "" {
+			return fmt.Errorf("[ERROR] testAccIfImageCopyExists failed, copy is created but ID not set")
+		}
+
+		image, err := queryImage(testAccProvider.Meta(), testAccImageCopyRegion, info.Primary.ID)
+		if err != nil {
+			return err
+		}
+		if image == nil {
+			return fmt.Errorf("[ERROR] testAccIfImageCopyExists failed, %s can not be found in %s", info.Primary.ID, testAccImageCopyRegion)
+		}
+		if image.Status != IMAGE_READY {
+			return fmt.Errorf("[ERROR] testAccIfImageCopyExists failed, expecting %s to be ready, got %s", info.Primary.ID, image.Status)
+		}
+
+		*imageId = info.Primary.ID
+		return nil
+	}
+}
+
+func testAccIfImageCopyDestroyed(imageId *string) resource.TestCheckFunc {
+
+	return func(stateInfo *terraform.State) error {
+
+		image, err := queryImage(testAccProvider.Meta(), testAccImageCopyRegion, *imageId)
+		if err != nil {
+			return err
+		}
+		if image != nil {
+			return fmt.Errorf("[ERROR] testAccIfImageCopyDestroyed failed, %s still exists", *imageId)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,224 @@
+package jdcloud
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/jdcloud-api/jdcloud-sdk-go/services/vm/apis"
+	"github.com/jdcloud-api/jdcloud-sdk-go/services/vm/client"
+	"time"
+)
+
+/*
+	An image share manages the whole member list of an image, hence its id is the image id.
+	Accounts shared or unshared outside of Terraform show up as drift on refresh.
+	Creation fails rather than taking over an image already shared with other accounts
+*/
+
+//----------------------------------------------------------------------------------- OTHERS
+
+// Level 0 -> Pins of the accounts an image is shared with
+func queryImageMembers(m interface{}, imageId string) (pins []string, e error) {
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	req := apis.NewDescribeImageMembersRequest(config.Region, imageId)
+
+	e = resource.Retry(time.Minute, func() *resource.RetryError {
+
+		resp, err := vmClient.DescribeImageMembers(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			pins = resp.Result.Pins
+			return nil
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+	return
+}
+
+// Level 0 -> Share an image with some accounts
+func shareImage(m interface{}, imageId string, pins []string) error {
+
+	if len(pins) == 0 {
+		return nil
+	}
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	vmClient.SetLogger(vmLogger{})
+	req := apis.NewShareImageRequestWithAllParams(config.Region, imageId, pins)
+
+	return resource.Retry(2*time.Minute, func() *resource.RetryError {
+
+		resp, err := vmClient.ShareImage(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			return nil
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+}
+
+// Level 0 -> Stop sharing an image with some accounts
+func unshareImage(m interface{}, imageId string, pins []string) error {
+
+	if len(pins) == 0 {
+		return nil
+	}
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	vmClient.SetLogger(vmLogger{})
+	req := apis.NewUnShareImageRequestWithAllParams(config.Region, imageId, pins)
+
+	return resource.Retry(2*time.Minute, func() *resource.RetryError {
+
+		resp, err := vmClient.UnShareImage(req)
+
+		if err == nil && (resp.Error.Code == REQUEST_COMPLETED || resp.Error.Code == RESOURCE_NOT_FOUND) {
+			return nil
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+}
+
+//----------------------------------------------------------------------------------- RESOURCE
+
+func resourceJDCloudImageShare() *schema.Resource {
+
+	return &schema.Resource{
+		Create: resourceJDCloudImageShareCreate,
+		Read:   resourceJDCloudImageShareRead,
+		Update: resourceJDCloudImageShareUpdate,
+		Delete: resourceJDCloudImageShareDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"image_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"pins": {
+				Type:     schema.TypeSet,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+		},
+	}
+}
+
+func resourceJDCloudImageShareCreate(d *schema.ResourceData, m interface{}) error {
+
+	imageId := d.Get("image_id").(string)
+
+	// Accounts shared with outside of Terraform are never unshared silently,
+	// the existing member list has to be imported first
+	existing, err := queryImageMembers(m, imageId)
+	if err != nil {
+		return err
+	}
+	pins := d.Get("pins").(*schema.Set)
+	unmanaged := []string{}
+	for _, pin := range existing {
+		if !pins.Contains(pin) {
+			unmanaged = append(unmanaged, pin)
+		}
+	}
+	if len(unmanaged) > 0 {
+		return fmt.Errorf("[ERROR] %s is already shared with %v, import it by terraform import jdcloud_image_share.<name> %s and list them in pins", imageId, unmanaged, imageId)
+	}
+
+	added := []string{}
+	for _, pin := range typeSetToStringArray(pins) {
+		if !stringInSlice(pin, existing) {
+			added = append(added, pin)
+		}
+	}
+
+	if err := shareImage(m, imageId, added); err != nil {
+		return err
+	}
+
+	d.SetId(imageId)
+	return resourceJDCloudImageShareRead(d, m)
+}
+
+func resourceJDCloudImageShareRead(d *schema.ResourceData, m interface{}) error {
+
+	image, err := queryImage(m, m.(*JDCloudConfig).Region, d.Id())
+	if err != nil {
+		return err
+	}
+	if image == nil {
+		d.SetId("")
+		return nil
+	}
+
+	pins, err := queryImageMembers(m, d.Id())
+	if err != nil {
+		return err
+	}
+
+	d.Set("image_id", d.Id())
+	if err := d.Set("pins", pins); err != nil {
+		return fmt.Errorf("[ERROR] Failed in setting pins, reasons:%s", err.Error())
+	}
+	return nil
+}
+
+func resourceJDCloudImageShareUpdate(d *schema.ResourceData, m interface{}) error {
+
+	if d.HasChange("pins") {
+
+		o, n := d.GetChange("pins")
+		removed := o.(*schema.Set).Difference(n.(*schema.Set))
+		added := n.(*schema.Set).Difference(o.(*schema.Set))
+
+		if err := unshareImage(m, d.Id(), typeSetToStringArray(removed)); err != nil {
+			return err
+		}
+		if err := shareImage(m, d.Id(), typeSetToStringArray(added)); err != nil {
+			return err
+		}
+	}
+	return resourceJDCloudImageShareRead(d, m)
+}
+
+func resourceJDCloudImageShareDelete(d *schema.ResourceData, m interface{}) error {
+
+	image, err := queryImage(m, m.(*JDCloudConfig).Region, d.Id())
+	if err != nil {
+		return err
+	}
+
+	if image != nil {
+		if err := unshareImage(m, d.Id(), typeSetToStringArray(d.Get("pins").(*schema.Set))); err != nil {
+			return err
+		}
+	}
+
+	d.SetId("")
+	return nil
+}
@@ -0,0 +1,113 @@
+package jdcloud
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"sort"
+	"strings"
+	"testing"
+)
+
+/*
+	TestCase : 1. Share an image with one account, then replace it with another
+*/
+
+const testAccImageShareTemplate = `
+resource "jdcloud_image" "image" {
+  instance_id = "%s"
+  image_name  = "%s"
+}
+
+resource "jdcloud_image_share" "share" {
+  image_id = "${jdcloud_image.image.id}"
+  pins     = ["%s"]
+}
+`
+
+func generateImageShareConfig(name, pin string) string {
+	return fmt.Sprintf(testAccImageShareTemplate, packer_instance, name, pin)
+}
+
+func TestAccJDCloudImageShare_basic(t *testing.T) {
+
+	var imageId string
+	name := randomStringWithLength(10)
+
+	resource.Test(t, resource.TestCase{
+
+		IDRefreshName: "jdcloud_image_share.share",
+		PreCheck:      func() { testAccPreCheck(t) },
+		Providers:     testAccProviders,
+		CheckDestroy:  testAccIfImageShareDestroyed(&imageId),
+		Steps: []resource.TestStep{
+			{
+				Config: generateImageShareConfig(name, "jdcloud-devops"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfImageShareExists("jdcloud_image_share.share", []string{"jdcloud-devops"}, &imageId),
+					resource.TestCheckResourceAttr("jdcloud_image_share.share", "pins.#", "1"),
+				),
+			},
+			{
+				Config: generateImageShareConfig(name, "jdcloud-partner"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfImageShareExists("jdcloud_image_share.share", []string{"jdcloud-partner"}, &imageId),
+					resource.TestCheckResourceAttr("jdcloud_image_share.share", "pins.#", "1"),
+				),
+			},
+			{
+				ResourceName:      "jdcloud_image_share.share",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testAccIfImageShareExists(name string, expected []string, imageId *string) resource.TestCheckFunc {
+
+	return func(stateInfo *terraform.State) error {
+
+		info, ok := stateInfo.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("[ERROR] testAccIfImageShareExists failed, resource %s not found in terraform.State", name)
+		}
+		if info.Primary.ID == "" {
+			return fmt.Errorf("[ERROR] testAccIfImageShareExists failed, share is created but ID not set")
+		}
+
+		pins, err := queryImageMembers(testAccProvider.Meta(), info.Primary.ID)
+		if err != nil {
+			return err
+		}
+		sort.Strings(pins)
+		sort.Strings(expected)
+		if strings.Join(pins, ",") != strings.Join(expected, ",") {
+			return fmt.Errorf("[ERROR] testAccIfImageShareExists failed, expecting members %v, got %v", expected, pins)
+		}
+
+		*imageId = info.Primary.ID
+		return nil
+	}
+}
+
+func testAccIfImageShareDestroyed(imageId *string) resource.TestCheckFunc {
+
+	return func(stateInfo *terraform.State) error {
+
+		config := testAccProvider.Meta().(*JDCloudConfig)
+		image, err := queryImage(config, config.Region, *imageId)
+		if err != nil || image == nil {
+			return err
+		}
+
+		pins, err := queryImageMembers(config, *imageId)
+		if err != nil {
+			return err
+		}
+		if len(pins) != 0 {
+			return fmt.Errorf("[ERROR] testAccIfImageShareDestroyed failed, %s is still shared with %v", *imageId, pins)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,108 @@
+package jdcloud
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	"testing"
+)
+
+/*
+	TestCase : 1. Image an instance, then rename it in place and import it
+*/
+
+const testAccImageTemplate = `
+resource "jdcloud_image" "image" {
+  instance_id = "%s"
+  image_name  = "%s"
+  description = "%s"
+}
+`
+
+func generateImageConfig(name, description string) string {
+	return fmt.Sprintf(testAccImageTemplate, packer_instance, name, description)
+}
+
+func TestAccJDCloudImage_basic(t *testing.T) {
+
+	var imageId string
+	name1 := randomStringWithLength(10)
+	name2 := randomStringWithLength(10)
+
+	resource.Test(t, resource.TestCase{
+
+		IDRefreshName: "jdcloud_image.image",
+		PreCheck:      func() { testAccPreCheck(t) },
+		Providers:     testAccProviders,
+		CheckDestroy:  testAccIfImageDestroyed(&imageId),
+		Steps: []resource.TestStep{
+			{
+				Config: generateImageConfig(name1, "first"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfImageExists("jdcloud_image.image", &imageId),
+					resource.TestCheckResourceAttr("jdcloud_image.image", "image_name", name1),
+					resource.TestCheckResourceAttrSet("jdcloud_image.image", "os_type"),
+				),
+			},
+			{
+				Config: generateImageConfig(name2, "second"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfImageExists("jdcloud_image.image", &imageId),
+					resource.TestCheckResourceAttr("jdcloud_image.image", "image_name", name2),
+					resource.TestCheckResourceAttr("jdcloud_image.image", "description", "second"),
+				),
+			},
+			{
+				ResourceName:            "jdcloud_image.image",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"instance_id"},
+			},
+		},
+	})
+}
+
+func testAccIfImageExists(name string, imageId *string) resource.TestCheckFunc {
+
+	return func(stateInfo *terraform.State) error {
+
+		info, ok := stateInfo.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("[ERROR] testAccIfImageExists failed, resource %s not found in terraform.State", name)
+		}
+		if info.Primary.ID == "" {
+			return fmt.Errorf("[ERROR] testAccIfImageExists failed, image is created but ID not set")
+		}
+
+		config := testAccProvider.Meta().(*JDCloudConfig)
+		image, err := queryImage(config, config.Region, info.Primary.ID)
+		if err != nil {
+			return err
+		}
+		if image == nil {
+			return fmt.Errorf("[ERROR] testAccIfImageExists failed, %s can not be found", info.Primary.ID)
+		}
+		if image.Name != info.Primary.Attributes["image_name"] {
+			return fmt.Errorf("[ERROR] testAccIfImageExists failed, local name %s != remote name %s", info.Primary.Attributes["image_name"], image.Name)
+		}
+
+		*imageId = info.Primary.ID
+		return nil
+	}
+}
+
+func testAccIfImageDestroyed(imageId *string) resource.TestCheckFunc {
+
+	return func(stateInfo *terraform.State) error {
+
+		config := testAccProvider.Meta().(*JDCloudConfig)
+		image, err := queryImage(config, config.Region, *imageId)
+		if err != nil {
+			return err
+		}
+		if image != nil {
+			return fmt.Errorf("[ERROR] testAccIfImageDestroyed failed, %s still exists", *imageId)
+		}
+		return nil
+	}
+}
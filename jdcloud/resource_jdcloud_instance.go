@@ -2,15 +2,22 @@ package jdcloud
 
 import (
 	"fmt"
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/jdcloud-api/jdcloud-sdk-go/core"
+	common "github.com/jdcloud-api/jdcloud-sdk-go/services/common/models"
+	diskApis "github.com/jdcloud-api/jdcloud-sdk-go/services/disk/apis"
+	diskClient "github.com/jdcloud-api/jdcloud-sdk-go/services/disk/client"
 	dm "github.com/jdcloud-api/jdcloud-sdk-go/services/disk/models"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/vm/apis"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/vm/client"
 	vm "github.com/jdcloud-api/jdcloud-sdk-go/services/vm/models"
+	vpcApis "github.com/jdcloud-api/jdcloud-sdk-go/services/vpc/apis"
+	vpcClient "github.com/jdcloud-api/jdcloud-sdk-go/services/vpc/client"
 	vpc "github.com/jdcloud-api/jdcloud-sdk-go/services/vpc/models"
 	"log"
+	"sync"
 	"time"
 )
 
@@ -91,32 +98,6 @@ func QueryInstanceDetail(d *schema.ResourceData, m interface{}, instanceId strin
 
 //----------------------------------------------------------------------------------- VM-RELATED
 
-func waitForInstance(d *schema.ResourceData, m interface{}, expectedStatus string) error {
-
-	config := m.(*JDCloudConfig)
-	vmClient := client.NewVmClient(config.Credential)
-	req := apis.NewDescribeInstanceRequest(config.Region, d.Id())
-
-	return resource.Retry(5*time.Minute, func() *resource.RetryError {
-
-		resp, err := vmClient.DescribeInstance(req)
-
-		if err == nil && resp.Error.Code == REQUEST_COMPLETED && resp.Result.Instance.Status == expectedStatus {
-			return nil
-		}
-
-		if expectedStatus == "" && resp.Result.Instance.Status == expectedStatus {
-			return nil
-		}
-
-		if connectionError(err) || resp.Result.Instance.Status != expectedStatus {
-			return resource.RetryableError(formatConnectionErrorMessage())
-		} else {
-			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
-		}
-	})
-}
-
 // Level 2 -> Based on instanceStatusWaiter
 
 func StopVmInstance(d *schema.ResourceData, m interface{}, instanceId string) error {
@@ -150,7 +131,7 @@ func StopVmInstance(d *schema.ResourceData, m interface{}, instanceId string) er
 	//return instanceStatusWaiter(d, m, d.Id(), []string{VM_RUNNING, VM_STOPPING}, []string{VM_STOPPED,VM_STOPPED_2})
 }
 
-func StartVmInstance(d *schema.ResourceData, m interface{}) error {
+func StartVmInstance(d *schema.ResourceData, m interface{}, timeout time.Duration) error {
 
 	config := m.(*JDCloudConfig)
 	vmClient := client.NewVmClient(config.Credential)
@@ -173,7 +154,7 @@ func StartVmInstance(d *schema.ResourceData, m interface{}) error {
 	if e != nil {
 		return e
 	}
-	return instanceStatusWaiter(d, m, d.Id(), []string{VM_STOPPED, VM_STARTING}, []string{VM_RUNNING})
+	return instanceStatusWaiter(d, m, d.Id(), []string{VM_STOPPED, VM_STARTING}, []string{VM_RUNNING}, timeout)
 }
 
 func DeleteVmInstance(d *schema.ResourceData, m interface{}, id string) error {
@@ -190,6 +171,8 @@ func DeleteVmInstance(d *schema.ResourceData, m interface{}, id string) error {
 			return nil
 		}
 
+		// Charge of a newly created instance may not be ready yet
+		// Prepaid instances are not supposed to reach here, see prepaidDestroyAllowed
 		if resp != nil && resp.Error.Code == REQUEST_INVALID_2 && resp.Error.Status == "PERMISSION_DENIED" {
 			return resource.RetryableError(fmt.Errorf("Can't delete no charged resource"))
 		}
@@ -218,67 +201,79 @@ func diskIdList(s *schema.Set) []string {
 	return i
 }
 
-// Used to refresh vm status level 0
+// Used to refresh vm status level 0 -> Based on the instance poller shared by this provider
+// Deleted instances, or those not yet visible, are reported as nil with status VM_DELETED
 func instanceStatusRefreshFunc(d *schema.ResourceData, meta interface{}, vmId string) resource.StateRefreshFunc {
 
 	return func() (vmItem interface{}, vmStatus string, e error) {
 
-		err := resource.Retry(time.Minute, func() *resource.RetryError {
-			config := meta.(*JDCloudConfig)
-			c := client.NewVmClient(config.Credential)
-			req := apis.NewDescribeInstanceRequest(config.Region, vmId)
-			resp, err := c.DescribeInstance(req)
-			if err == nil && resp.Error.Code == REQUEST_COMPLETED {
-				vmItem = resp.Result.Instance
-				vmStatus = resp.Result.Instance.Status
-				return nil
-			}
-
-			if connectionError(err) {
-				return resource.RetryableError(err)
-			} else {
-				vmItem = resp.Result.Instance
-				vmStatus = resp.Result.Instance.Status
-				return resource.NonRetryableError(err)
-			}
-
-		})
-
+		config := meta.(*JDCloudConfig)
+		item, err := config.instancePoller.get(vmId)
 		if err != nil {
 			return nil, "", err
 		}
-		return vmItem, vmStatus, nil
+		if item == nil {
+			return nil, VM_DELETED, nil
+		}
+		return item, item.(vm.Instance).Status, nil
 	}
 }
 
 // Used to refresh until instance reached expected status level 1 -> Based on instanceStatusRefreshFunc
-func instanceStatusWaiter(d *schema.ResourceData, meta interface{}, id string, pending, target []string) (err error) {
+func instanceStatusWaiter(d *schema.ResourceData, meta interface{}, id string, pending, target []string, timeout time.Duration) (err error) {
 
-	stateConf := &resource.StateChangeConf{
-		Pending:    pending,
-		Target:     target,
-		Refresh:    instanceStatusRefreshFunc(d, meta, id),
-		Delay:      3 * time.Second,
-		Timeout:    10 * time.Minute,
-		MinTimeout: 1 * time.Second,
-	}
-	if _, err = stateConf.WaitForState(); err != nil {
+	if err = waitForRefresh(id, instanceStatusRefreshFunc(d, meta, id), pending, target, timeout); err != nil {
 		return fmt.Errorf("[E] Failed in instanceStatusWaiter/Waiting to reach expected status ,err message:%v", err)
 	}
 	return nil
 }
 
-// Level 2~3  delete a specified instance
-func deleteInstance(d *schema.ResourceData, m interface{}, instanceId string) error {
+// Level 0 -> Query instances in batches, used by the instance poller. Deleted ones are missing from the result
+func queryInstances(m interface{}, instanceIds []string) (map[string]interface{}, error) {
 
-	// Stop VM
-	err := StopVmInstance(d, m, instanceId)
-	if err != nil {
-		return fmt.Errorf("[E] deleteInstance - InstanceId=%s - Send request to stop fail:%v", instanceId, err)
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	instances := make(map[string]interface{}, len(instanceIds))
+
+	for start := 0; start < len(instanceIds); start += MAX_VM_PAGE_SIZE {
+
+		end := start + MAX_VM_PAGE_SIZE
+		if end > len(instanceIds) {
+			end = len(instanceIds)
+		}
+		req := apis.NewDescribeInstancesRequestWithAllParams(config.Region, nil, intAddr(MAX_VM_PAGE_SIZE), []common.Filter{
+			{Name: "instanceId", Values: instanceIds[start:end]},
+		})
+
+		e := resource.Retry(time.Minute, func() *resource.RetryError {
+
+			resp, err := vmClient.DescribeInstances(req)
+
+			if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+				for _, instance := range resp.Result.Instances {
+					instances[instance.InstanceId] = instance
+				}
+				return nil
+			}
+
+			if connectionError(err) {
+				return resource.RetryableError(formatConnectionErrorMessage())
+			} else {
+				return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+			}
+		})
+		if e != nil {
+			return nil, e
+		}
 	}
+	return instances, nil
+}
 
-	// Wait until stopped
-	err = instanceStatusWaiter(d, m, instanceId, []string{VM_RUNNING, VM_STOPPING}, []string{VM_STOPPED, VM_STOPPED_2})
+// Level 2~3  delete a specified instance, it has to be done before deadline
+func deleteInstance(d *schema.ResourceData, m interface{}, instanceId string, deadline time.Time) error {
+
+	// Stop VM unless it is stopped already, e.g. instance_state = "stopped"
+	err := ensureVmStopped(d, m, instanceId, time.Until(deadline))
 	if err != nil {
 		return fmt.Errorf("[E] deleteInstance - InstanceId=%s - Can not make it stop :%v", instanceId, err)
 	}
@@ -290,43 +285,332 @@ func deleteInstance(d *schema.ResourceData, m interface{}, instanceId string) er
 	}
 
 	// Wait until deleted
-	if err = instanceStatusWaiter(d, m, instanceId, []string{VM_RUNNING, VM_STOPPING, VM_DELETING}, []string{VM_DELETED}); err != nil {
+	if err = instanceStatusWaiter(d, m, instanceId, []string{VM_RUNNING, VM_STOPPING, VM_DELETING}, []string{VM_DELETED}, time.Until(deadline)); err != nil {
 		return fmt.Errorf("[E] deleteInstance - InstanceId=%s - Can not wait it delete :%v", instanceId, err)
 	}
 
 	return nil
 }
 
-// Level 2~3 delete some instances
-func deleteInstances(d *schema.ResourceData, m interface{}, instanceIds []string) error {
+// Level 2~3 delete some instances, at most MAX_VM_PARALLEL of them are in progress at the same time
+// Failures do not stop the others, they are reported together in the end
+func deleteInstances(d *schema.ResourceData, m interface{}, instanceIds []string, timeout time.Duration) error {
+
+	deadline := time.Now().Add(timeout)
+
+	var errs *multierror.Error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, MAX_VM_PARALLEL)
+
+	for _, id := range instanceIds {
+		wg.Add(1)
+		go func(instanceId string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if err := deleteInstance(d, m, instanceId, deadline); err != nil {
+				mu.Lock()
+				errs = multierror.Append(errs, err)
+				mu.Unlock()
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	return errs.ErrorOrNil()
+}
+
+// Level 1 -> Wait until some instances are running. They share the instance poller, hence one query per round
+func waitForInstancesRunning(d *schema.ResourceData, m interface{}, instanceIds []string, timeout time.Duration) error {
+
+	var errs *multierror.Error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, id := range instanceIds {
+		wg.Add(1)
+		go func(instanceId string) {
+			defer wg.Done()
+			if err := instanceStatusWaiter(d, m, instanceId, []string{VM_PENDING, VM_STARTING}, []string{VM_RUNNING}, timeout); err != nil {
+				mu.Lock()
+				errs = multierror.Append(errs, err)
+				mu.Unlock()
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	return errs.ErrorOrNil()
+}
+
+// Level 0 -> Query the spec of given instance types
+func queryInstanceTypes(m interface{}, instanceTypes []string) (types []vm.InstanceType, e error) {
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	req := apis.NewDescribeInstanceTypesRequestWithAllParams(config.Region, []common.Filter{
+		{Name: "instanceTypes", Values: instanceTypes},
+	})
+
+	e = resource.Retry(time.Minute, func() *resource.RetryError {
 
-	// Stop
-	for _, i := range instanceIds {
-		if e := StopVmInstance(d, m, i); e != nil {
-			return fmt.Errorf("[E] deleteInstances - Send request to stop fail:%s", e)
+		resp, err := vmClient.DescribeInstanceTypes(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			types = resp.Result.InstanceTypes
+			return nil
 		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+	return types, e
+}
+
+// Level 0 -> Query instance types that can be used together with an image
+func queryImageConstraint(m interface{}, imageId string) (c vm.ImageInstanceTypeConstraint, e error) {
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	req := apis.NewDescribeImageConstraintsRequest(config.Region, imageId)
+
+	e = resource.Retry(time.Minute, func() *resource.RetryError {
+
+		resp, err := vmClient.DescribeImageConstraints(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			c = resp.Result.ImageConstraints.ImageInstanceTypeConstraint
+			return nil
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+	return c, e
+}
+
+// Level 1 -> Reject resizing that platform does not support, used in CustomizeDiff
+//  1. Target type has to be in stock in the az of this instance
+//  2. GPU types can neither be resized, nor be resized to
+//  3. Target type has to be compatible with the image
+func verifyInstanceResize(m interface{}, az, imageId, from, to string) error {
+
+	types, err := queryInstanceTypes(m, []string{from, to})
+	if err != nil {
+		return err
 	}
 
-	//Wait until stopped
-	for _, i := range instanceIds {
-		if err := instanceStatusWaiter(d, m, i, []string{VM_RUNNING, VM_STOPPING}, []string{VM_STOPPED, VM_STOPPED_2}); err != nil {
-			return fmt.Errorf("[E] deleteInstances - Can not make it stop :%s", err)
+	var source, target *vm.InstanceType
+	for index := range types {
+		if types[index].InstanceType == from {
+			source = &types[index]
+		}
+		if types[index].InstanceType == to {
+			target = &types[index]
 		}
 	}
 
-	// Delete
-	for _, i := range instanceIds {
-		if e := DeleteVmInstance(d, m, i); e != nil {
-			return fmt.Errorf("[E] deleteInstances - Can not send requests to delete:%v", e)
+	if target == nil {
+		return fmt.Errorf("[ERROR] Can not resize to %s, this instance type is not available in current region", to)
+	}
+
+	inStock := false
+	for _, state := range target.State {
+		if state.Az == az && state.InStock {
+			inStock = true
 		}
 	}
+	if !inStock {
+		return fmt.Errorf("[ERROR] Can not resize to %s, this instance type is sold out in %s", to, az)
+	}
 
-	// Wait until deleted
-	for _, i := range instanceIds {
-		if e := instanceStatusWaiter(d, m, i, []string{VM_RUNNING, VM_STOPPING, VM_DELETING}, []string{VM_DELETED}); e != nil {
-			return fmt.Errorf("[E] deleteInstances - Can not wait it delete %v", e)
+	if target.Gpu.Number > 0 || (source != nil && source.Gpu.Number > 0) {
+		return fmt.Errorf("[ERROR] Can not resize from %s to %s, GPU instance types do not support resizing", from, to)
+	}
+
+	constraint, err := queryImageConstraint(m, imageId)
+	if err != nil {
+		return err
+	}
+
+	listed := false
+	for _, t := range constraint.InstanceTypes {
+		if t == to {
+			listed = true
 		}
 	}
+	if (constraint.ConstraintsType == "includes" && !listed) || (constraint.ConstraintsType == "excludes" && listed) {
+		return fmt.Errorf("[ERROR] Can not resize to %s, this instance type is not supported by image %s", to, imageId)
+	}
+
+	return nil
+}
+
+// Level 0 -> Query the elastic ip associated with instance
+func queryElasticIp(m interface{}, elasticIpId string) (eip vpc.ElasticIp, e error) {
+
+	config := m.(*JDCloudConfig)
+	req := vpcApis.NewDescribeElasticIpRequest(config.Region, elasticIpId)
+
+	e = resource.Retry(time.Minute, func() *resource.RetryError {
+
+		resp, err := vpcClient.NewVpcClient(config.Credential).DescribeElasticIp(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			eip = resp.Result.ElasticIp
+			return nil
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+	return eip, e
+}
+
+// Level 1 -> Stop the instance if it is running, wait until stopped
+func ensureVmStopped(d *schema.ResourceData, m interface{}, instanceId string, timeout time.Duration) error {
+
+	resp, err := QueryInstanceDetail(d, m, instanceId)
+	if err != nil {
+		return err
+	}
+	status := resp.Result.Instance.Status
+	if status != VM_RUNNING && status != VM_STOPPING {
+		return nil
+	}
+
+	if status == VM_RUNNING {
+		if err := StopVmInstance(d, m, instanceId); err != nil {
+			return fmt.Errorf("stop instance got error:%s", err)
+		}
+	}
+	if err := instanceStatusWaiter(d, m, instanceId, []string{VM_RUNNING, VM_STOPPING}, []string{VM_STOPPED, VM_STOPPED_2}, timeout); err != nil {
+		return fmt.Errorf("stop instance got error(2):%s", err)
+	}
+	return nil
+}
+
+// Level 1 -> Start or stop the instance so that it ends up in expected status
+func setVmPowerState(d *schema.ResourceData, m interface{}, expectedStatus string, timeout time.Duration) error {
+
+	resp, err := QueryInstanceDetail(d, m, d.Id())
+	if err != nil {
+		return err
+	}
+	currentStatus := resp.Result.Instance.Status
+
+	if expectedStatus == VM_RUNNING && currentStatus == VM_STOPPED {
+		if err := StartVmInstance(d, m, timeout); err != nil {
+			return fmt.Errorf("start instance got error:%s", err)
+		}
+	}
+	if expectedStatus == VM_STOPPED && currentStatus == VM_RUNNING {
+		return ensureVmStopped(d, m, d.Id(), timeout)
+	}
+	return nil
+}
+
+// Level 2 -> Resize a stopped instance, wait until resizing completed
+func resizeVmInstance(d *schema.ResourceData, m interface{}, instanceType string, timeout time.Duration) error {
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	req := apis.NewResizeInstanceRequest(config.Region, d.Id(), instanceType)
+
+	err := resource.Retry(time.Minute, func() *resource.RetryError {
+
+		resp, err := vmClient.ResizeInstance(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			return nil
+		}
+
+		if resp != nil && resp.Error.Code == REQUEST_INVALID && resp.Error.Status == "FAILED_PRECONDITION" {
+			return resource.RetryableError(fmt.Errorf("Conflict with underlay task"))
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Status remains "stopped" for a while before it turns into "resizing"
+	// Therefore instance type is also checked to make sure resizing has finished
+	refresh := instanceStatusRefreshFunc(d, m, d.Id())
+	resized := func() (interface{}, string, error) {
+		vmItem, vmStatus, err := refresh()
+		if err == nil && vmStatus == VM_STOPPED && vmItem.(vm.Instance).InstanceType == instanceType {
+			return vmItem, "resized", nil
+		}
+		return vmItem, vmStatus, err
+	}
+	if err = waitForRefresh(d.Id(), resized, []string{VM_STOPPED, VM_RESIZING}, []string{"resized"}, timeout); err != nil {
+		return fmt.Errorf("[E] Failed in resizeVmInstance/Waiting resizing to complete ,err message:%v", err)
+	}
+	return nil
+}
+
+// Level 2 -> Reinstall the OS of a stopped instance, wait until rebuilding completed
+func rebuildVmInstance(d *schema.ResourceData, m interface{}, imageId string, timeout time.Duration) error {
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	req := apis.NewRebuildInstanceRequest(config.Region, d.Id(), d.Get("password").(string))
+	req.ImageId = &imageId
+	if keyNames, ok := d.GetOk("key_names"); ok {
+		req.KeyNames = typeSetToStringArray(keyNames.(*schema.Set))
+	}
+
+	err := resource.Retry(time.Minute, func() *resource.RetryError {
+
+		resp, err := vmClient.RebuildInstance(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			return nil
+		}
+
+		if resp != nil && resp.Error.Code == REQUEST_INVALID && resp.Error.Status == "FAILED_PRECONDITION" {
+			return resource.RetryableError(fmt.Errorf("Conflict with underlay task"))
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Similar to resizing, image id is checked to make sure rebuilding has finished
+	refresh := instanceStatusRefreshFunc(d, m, d.Id())
+	rebuilt := func() (interface{}, string, error) {
+		vmItem, vmStatus, err := refresh()
+		if err == nil && (vmStatus == VM_STOPPED || vmStatus == VM_RUNNING) && vmItem.(vm.Instance).ImageId == imageId {
+			return vmItem, "rebuilt", nil
+		}
+		return vmItem, vmStatus, err
+	}
+	if err = waitForRefresh(d.Id(), rebuilt, []string{VM_STOPPED, VM_REBUILDING, VM_STARTING, VM_RUNNING}, []string{"rebuilt"}, timeout); err != nil {
+		return fmt.Errorf("[E] Failed in rebuildVmInstance/Waiting rebuilding to complete ,err message:%v", err)
+	}
 	return nil
 }
 
@@ -408,6 +692,7 @@ func cloudDiskStructIntoMap(ss []vm.InstanceDiskAttachment) []map[string]interfa
 					"description":   s.CloudDisk.Description,
 					"disk_type":     s.CloudDisk.DiskType,
 					"disk_size_gb":  s.CloudDisk.DiskSizeGB,
+					"snapshot_id":   s.CloudDisk.SnapshotId,
 				})
 			} else {
 
@@ -426,110 +711,346 @@ func cloudDiskStructIntoMap(ss []vm.InstanceDiskAttachment) []map[string]interfa
 			}
 
 		}
-	}
+	}
+
+	return ms
+}
+
+func sysDiskStructIntoMap(s vm.InstanceDiskAttachment) map[string]interface{} {
+
+	return map[string]interface{}{
+		"disk_category": s.DiskCategory,
+		"auto_delete":   s.AutoDelete,
+		"device_name":   s.DeviceName,
+		"disk_size_gb":  s.LocalDisk.DiskSizeGB,
+	}
+}
+
+func waitCloudDiskId(d *schema.ResourceData, m interface{}) error {
+
+	resp, err := QueryInstanceDetail(d, m, d.Id())
+
+	if err != nil || resp.Error.Code != REQUEST_COMPLETED {
+		return err
+	}
+
+	if errSet := d.Set("data_disk", cloudDiskStructIntoMap(resp.Result.Instance.DataDisks)); err != nil {
+		return errSet
+	}
+
+	return nil
+}
+
+// Data disks are identified by their device name
+func dataDiskMapByDevice(disks []interface{}) map[string]map[string]interface{} {
+
+	ms := map[string]map[string]interface{}{}
+	for _, item := range disks {
+		m := item.(map[string]interface{})
+		ms[m["device_name"].(string)] = m
+	}
+	return ms
+}
+
+// Level 0 -> The first field of an existing cloud disk that can not be modified in place, "" if none.
+// A disk is never replaced behind an in-place plan, disk_size_gb can only be increased by extending it
+func dataDiskUnmodifiable(o, n map[string]interface{}) string {
+
+	for _, key := range []string{"disk_category", "disk_type", "snapshot_id"} {
+		if o[key] != n[key] {
+			return key
+		}
+	}
+	if n["az"] != "" && o["az"] != n["az"] {
+		return "az"
+	}
+	if n["disk_size_gb"].(int) < o["disk_size_gb"].(int) {
+		return "disk_size_gb"
+	}
+	return ""
+}
+
+// Level 0 -> Reject data disk changes that platform does not support, used in CustomizeDiff
+//  1. Data disks of an existing instance are identified by device name, therefore it is required
+//  2. Local disks can only be specified on creation, they can neither be added, removed nor modified
+//  3. Cloud disks keep their data, only disk_size_gb can be increased besides names and auto_delete
+func verifyDataDiskChange(o, n []interface{}) error {
+
+	devices := map[string]bool{}
+	for _, item := range n {
+		device := item.(map[string]interface{})["device_name"].(string)
+		if device == "" {
+			return fmt.Errorf("[ERROR] device_name is required on data_disk when modifying data disks of an existing instance")
+		}
+		if devices[device] {
+			return fmt.Errorf("[ERROR] device_name %s is specified more than once on data_disk", device)
+		}
+		devices[device] = true
+	}
+
+	oldDisks := dataDiskMapByDevice(o)
+	newDisks := dataDiskMapByDevice(n)
+
+	for device, od := range oldDisks {
+		nd, ok := newDisks[device]
+		if od["disk_category"] != DISKTYPE_LOCAL {
+			if ok {
+				if key := dataDiskUnmodifiable(od, nd); key != "" {
+					return fmt.Errorf("[ERROR] %s of the cloud disk on %s can not be modified, disk_size_gb can only be increased. "+
+						"To replace the disk, remove it and add a new one on another device_name", key, device)
+				}
+			}
+			continue
+		}
+		if !ok || dataDiskUnmodifiable(od, nd) != "" || od["disk_size_gb"] != nd["disk_size_gb"] || od["auto_delete"] != nd["auto_delete"] {
+			return fmt.Errorf("[ERROR] Local disk on %s can not be removed or modified after the instance is created", device)
+		}
+	}
+	for device, nd := range newDisks {
+		if _, ok := oldDisks[device]; !ok && nd["disk_category"] == DISKTYPE_LOCAL {
+			return fmt.Errorf("[ERROR] Local disk on %s can not be added after the instance is created", device)
+		}
+	}
+	return nil
+}
+
+// Level 2 -> Detach a cloud disk from this instance and delete it
+func removeInstanceDataDisk(d *schema.ResourceData, m interface{}, diskId string, timeout time.Duration) error {
+
+	if err := performDiskDetach(m, diskId, d.Id(), false, timeout); err != nil {
+		return err
+	}
+	if err := diskAttachmentWaiter(d, m, d.Id(), diskId, []string{DISK_ATTACHED, DISK_DETACHING}, []string{DISK_DETACHED}, timeout); err != nil {
+		return err
+	}
+	if err := performDiskDelete(d, m, diskId); err != nil {
+		return err
+	}
+	return diskStatusWaiter(d, m, diskId, []string{DISK_AVAILABLE, DISK_DELETING}, []string{DISK_DELETED}, timeout)
+}
+
+// Level 2 -> Create a cloud disk and attach it to this instance
+func addInstanceDataDisk(d *schema.ResourceData, m interface{}, disk map[string]interface{}, timeout time.Duration) error {
+
+	spec := typeListToDiskList([]interface{}{disk})[0]
+	if spec.CloudDiskSpec.Az == "" {
+		spec.CloudDiskSpec.Az = d.Get("az").(string)
+	}
+	if spec.CloudDiskSpec.Name == "" {
+		spec.CloudDiskSpec.Name = fmt.Sprintf("%s-%s", d.Get("instance_name").(string), disk["device_name"].(string))
+	}
+
+	diskId, err := performDiskCreate(d, m, spec.CloudDiskSpec)
+	if err != nil {
+		return err
+	}
+	if err := diskStatusWaiter(d, m, diskId, []string{DISK_CREATING}, []string{DISK_AVAILABLE}, timeout); err != nil {
+		return err
+	}
+	if _, err := performDiskAttach(m, diskId, d.Id(), disk["device_name"].(string), disk["auto_delete"].(bool), timeout); err != nil {
+		return err
+	}
+	return diskAttachmentWaiter(d, m, d.Id(), diskId, []string{DISK_ATTACHING}, []string{DISK_ATTACHED}, timeout)
+}
+
+// Level 1 -> Modify auto_delete of attached cloud disks
+func modifyInstanceDiskAutoDelete(d *schema.ResourceData, m interface{}, attributes []vm.InstanceDiskAttribute) error {
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	req := apis.NewModifyInstanceDiskAttributeRequestWithAllParams(config.Region, d.Id(), attributes)
+
+	return resource.Retry(time.Minute, func() *resource.RetryError {
+
+		resp, err := vmClient.ModifyInstanceDiskAttribute(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			return nil
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+}
+
+// Level 1 -> Modify name and description of an attached cloud disk
+func modifyInstanceDiskAttribute(m interface{}, diskId, name, description string) error {
+
+	config := m.(*JDCloudConfig)
+	c := diskClient.NewDiskClient(config.Credential)
+	req := diskApis.NewModifyDiskAttributeRequestWithAllParams(config.Region, diskId, &name, &description)
+
+	return resource.Retry(time.Minute, func() *resource.RetryError {
+
+		resp, err := c.ModifyDiskAttribute(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			return nil
+		}
 
-	return ms
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
 }
 
-func sysDiskStructIntoMap(s vm.InstanceDiskAttachment) map[string]interface{} {
+// Level 3 -> Apply changes on data disks, verified by verifyDataDiskChange beforehand
+// Removed disks are detached and deleted first, then new disks are created and attached, existing ones are extended
+func updateInstanceDataDisks(d *schema.ResourceData, m interface{}, timeout time.Duration) error {
 
-	return map[string]interface{}{
-		"disk_category": s.DiskCategory,
-		"auto_delete":   s.AutoDelete,
-		"device_name":   s.DeviceName,
-		"disk_size_gb":  s.LocalDisk.DiskSizeGB,
+	o, n := d.GetChange("data_disk")
+	oldDisks := dataDiskMapByDevice(o.([]interface{}))
+	newDisks := dataDiskMapByDevice(n.([]interface{}))
+
+	for device, od := range oldDisks {
+		if _, ok := newDisks[device]; ok {
+			continue
+		}
+		if err := removeInstanceDataDisk(d, m, od["disk_id"].(string), timeout); err != nil {
+			return err
+		}
 	}
-}
 
-func waitCloudDiskId(d *schema.ResourceData, m interface{}) error {
+	attributes := []vm.InstanceDiskAttribute{}
+	for device, nd := range newDisks {
 
-	resp, err := QueryInstanceDetail(d, m, d.Id())
+		od, ok := oldDisks[device]
+		if !ok {
+			if err := addInstanceDataDisk(d, m, nd, timeout); err != nil {
+				return err
+			}
+			continue
+		}
 
-	if err != nil || resp.Error.Code != REQUEST_COMPLETED {
-		return err
+		diskId := od["disk_id"].(string)
+		if size := nd["disk_size_gb"].(int); size > od["disk_size_gb"].(int) {
+			if err := performDiskExtend(m, diskId, size); err != nil {
+				return err
+			}
+		}
+		if od["auto_delete"] != nd["auto_delete"] {
+			autoDelete := nd["auto_delete"].(bool)
+			attributes = append(attributes, vm.InstanceDiskAttribute{DiskId: &diskId, AutoDelete: &autoDelete})
+		}
+		name := nd["disk_name"].(string)
+		if name == "" {
+			name = od["disk_name"].(string)
+		}
+		if od["disk_name"] != name || od["description"] != nd["description"] {
+			if err := modifyInstanceDiskAttribute(m, diskId, name, nd["description"].(string)); err != nil {
+				return err
+			}
+		}
 	}
 
-	if errSet := d.Set("data_disk", cloudDiskStructIntoMap(resp.Result.Instance.DataDisks)); err != nil {
-		return errSet
+	if len(attributes) > 0 {
+		return modifyInstanceDiskAutoDelete(d, m, attributes)
 	}
-
 	return nil
 }
 
 //----------------------------------------------------------------------------------- RESOURCE
 
-func resourceJDCloudInstance() *schema.Resource {
+func instanceDiskSchema(forceNew bool) *schema.Resource {
 
-	diskSchema := &schema.Resource{
+	return &schema.Resource{
 		Schema: map[string]*schema.Schema{
 
 			"disk_category": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
+				ForceNew: forceNew,
 			},
 			"auto_delete": {
 				Type:     schema.TypeBool,
 				Optional: true,
 				Computed: true,
-				ForceNew: true,
+				ForceNew: forceNew,
 			},
 			"device_name": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
+				ForceNew: forceNew,
 			},
 			"az": {
 				Type:     schema.TypeString,
 				Optional: true,
 				Computed: true,
-				ForceNew: true,
+				ForceNew: forceNew,
 			},
 			"disk_name": {
 				Type:     schema.TypeString,
 				Optional: true,
 				Computed: true,
-				ForceNew: true,
+				ForceNew: forceNew,
 			},
 			"description": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
+				ForceNew: forceNew,
 			},
 			"disk_type": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
+				ForceNew: forceNew,
 			},
 			"disk_size_gb": {
 				Type:     schema.TypeInt,
 				Optional: true,
-				ForceNew: true,
+				ForceNew: forceNew,
 				Default:  40,
 			},
 			"snapshot_id": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
+				ForceNew: forceNew,
 			},
 			"disk_id": {
 				Type:     schema.TypeString,
 				Computed: true,
-				ForceNew: true,
+				ForceNew: forceNew,
 			},
 		},
 	}
+}
 
-	return &schema.Resource{
+// Attributes of the primary network interface that are only sent on creation, e.g. sanity_check
+// Modifying them afterwards should neither update nor replace the instance
+func createOnlyDiffSuppress(k, old, new string, d *schema.ResourceData) bool {
+	return d.Id() != ""
+}
+
+func resourceJDCloudInstance() *schema.Resource {
+
+	diskSchema := instanceDiskSchema(true)
+
+	// Data disks are updated incrementally, see updateInstanceDataDisks
+	dataDiskSchema := instanceDiskSchema(false)
+
+	r := &schema.Resource{
 		Create: resourceJDCloudInstanceCreate,
 		Read:   resourceJDCloudInstanceRead,
 		Update: resourceJDCloudInstanceUpdate,
 		Delete: resourceJDCloudInstanceDelete,
 
+		CustomizeDiff: resourceJDCloudInstanceCustomizeDiff,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(VM_TIMEOUT * time.Second),
+			Update: schema.DefaultTimeout(VM_TIMEOUT * time.Second),
+			Delete: schema.DefaultTimeout(VM_TIMEOUT * time.Second),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"az": {
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
+				Computed: true,
 				ForceNew: true,
 			},
 			"instance_name": {
@@ -538,17 +1059,41 @@ func resourceJDCloudInstance() *schema.Resource {
 			},
 			"instance_type": {
 				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Optional: true,
+				Computed: true,
 			},
 			"image_id": {
 				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Optional: true,
+				Computed: true,
+			},
+			"rebuild_on_image_change": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"instance_state": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      VM_RUNNING,
+				ValidateFunc: validateStringCandidates(VM_RUNNING, VM_STOPPED),
 			},
 			"subnet_id": {
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			// Fields above are supplied by the template if not specified
+			"instance_template_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"availability_group_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
 				ForceNew: true,
 			},
 			"description": {
@@ -562,10 +1107,10 @@ func resourceJDCloudInstance() *schema.Resource {
 				Sensitive: true,
 			},
 
-			"key_names": { //Only one key pair name is supported
-				Type:     schema.TypeString,
+			"key_names": {
+				Type:     schema.TypeSet,
 				Optional: true,
-				ForceNew: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
 			},
 
 			"primary_ip": {
@@ -578,18 +1123,33 @@ func resourceJDCloudInstance() *schema.Resource {
 			"security_group_ids": {
 				Type:     schema.TypeSet,
 				Optional: true,
+				Computed: true,
 				MinItems: 1,
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
 				},
-				ForceNew: true,
 				MaxItems: MAX_SECURITY_GROUP_COUNT,
 			},
 
 			"network_interface_name": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ForceNew: true,
+			},
+			// Only applies on create, ModifyNetworkInterface can not change it. Changes on existing instances
+			// are suppressed rather than planned, they are neither applied nor replace the instance
+			"sanity_check": {
+				Type:             schema.TypeInt,
+				Optional:         true,
+				Computed:         true,
+				DiffSuppressFunc: createOnlyDiffSuppress,
+			},
+			"network_interface_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"mac_address": {
+				Type:     schema.TypeString,
+				Computed: true,
 			},
 
 			// You set : secondary_ip_count (Optional)
@@ -618,10 +1178,22 @@ func resourceJDCloudInstance() *schema.Resource {
 				Optional: true,
 				ForceNew: true,
 			},
+			// Instance does not support postpaid_by_usage
+			"charge":            chargeSchema(CHARGE_PREPAID_BY_DURATION, CHARGE_POSTPAID_BY_DURATION),
+			"elastic_ip_charge": chargeSchema(CHARGE_PREPAID_BY_DURATION, CHARGE_POSTPAID_BY_USAGE, CHARGE_POSTPAID_BY_DURATION),
+
+			"prepaid_on_destroy":  prepaidOnDestroySchema(),
+			"deletion_protection": deletionProtectionSchema(),
+			"tags": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
 
 			"system_disk": {
 				Type:     schema.TypeList,
 				Optional: true,
+				Computed: true,
 				MinItems: 1,
 				Elem:     diskSchema,
 				MaxItems: MAX_SYSDISK_COUNT,
@@ -631,10 +1203,97 @@ func resourceJDCloudInstance() *schema.Resource {
 				Type:     schema.TypeList,
 				MinItems: 1,
 				Optional: true,
-				Elem:     diskSchema,
+				Computed: true,
+				Elem:     dataDiskSchema,
 			},
 		},
 	}
+
+	// key_names used to be a single string
+	r.SchemaVersion = 1
+	r.StateUpgraders = []schema.StateUpgrader{
+		{
+			Version: 0,
+			Type:    resourceJDCloudInstanceV0(r.Schema).CoreConfigSchema().ImpliedType(),
+			Upgrade: stringToListStateUpgradeFunc("key_names"),
+		},
+	}
+	return r
+}
+
+// Schema of version 0, where key_names is a string
+func resourceJDCloudInstanceV0(current map[string]*schema.Schema) *schema.Resource {
+
+	s := make(map[string]*schema.Schema, len(current))
+	for k, v := range current {
+		s[k] = v
+	}
+	s["key_names"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Optional: true,
+	}
+	return &schema.Resource{Schema: s}
+}
+
+// Without a template, these fields have to be specified on creation
+func verifyInstanceTemplateFields(d *schema.ResourceDiff) error {
+
+	if _, ok := d.GetOk("instance_template_id"); ok || !d.NewValueKnown("instance_template_id") {
+		return nil
+	}
+
+	for _, key := range []string{"az", "instance_type", "image_id", "subnet_id"} {
+		if d.NewValueKnown(key) && d.Get(key).(string) == "" {
+			return fmt.Errorf("[ERROR] %s is required when instance_template_id is not specified", key)
+		}
+	}
+	return nil
+}
+
+func resourceJDCloudInstanceCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+
+	for _, key := range []string{"charge", "elastic_ip_charge"} {
+		if err := chargeCustomizeDiff(key, false)(d, m); err != nil {
+			return err
+		}
+	}
+	if d.Id() == "" {
+		return verifyInstanceTemplateFields(d)
+	}
+
+	// Image and key pair can only be modified through rebuilding, which is opt-in
+	rebuild := d.Get("rebuild_on_image_change").(bool) && d.HasChange("image_id")
+	if d.HasChange("image_id") && !rebuild {
+		if err := d.ForceNew("image_id"); err != nil {
+			return err
+		}
+	}
+	if d.HasChange("key_names") && !rebuild {
+		if err := d.ForceNew("key_names"); err != nil {
+			return err
+		}
+	}
+
+	// Changes on these fields lead to a new instance, no need to verify resizing
+	if d.HasChange("az") || d.HasChange("subnet_id") || ((d.HasChange("image_id") || d.HasChange("key_names")) && !rebuild) {
+		return nil
+	}
+
+	if d.HasChange("data_disk") {
+		o, n := d.GetChange("data_disk")
+		if err := verifyDataDiskChange(o.([]interface{}), n.([]interface{})); err != nil {
+			return err
+		}
+	}
+
+	if d.HasChange("instance_type") && d.NewValueKnown("instance_type") && d.NewValueKnown("image_id") {
+		o, n := d.GetChange("instance_type")
+		if err := verifyInstanceResize(m, d.Get("az").(string), d.Get("image_id").(string), o.(string), n.(string)); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func resourceJDCloudInstanceCreate(d *schema.ResourceData, m interface{}) error {
@@ -646,14 +1305,24 @@ func resourceJDCloudInstanceCreate(d *schema.ResourceData, m interface{}) error
 
 	// Preparing necessary parameters
 	spec := vm.InstanceSpec{
-		Az:           GetStringAddr(d, "az"),
-		InstanceType: GetStringAddr(d, "instance_type"),
-		ImageId:      GetStringAddr(d, "image_id"),
-		Name:         d.Get("instance_name").(string),
-		PrimaryNetworkInterface: &vm.InstanceNetworkInterfaceAttachmentSpec{
-			NetworkInterface: &vpc.NetworkInterfaceSpec{SubnetId: d.Get("subnet_id").(string)},
-			//NetworkInterface: &vpc.NetworkInterfaceSpec{SubnetId: d.Get("subnet_id").(string), Az: GetStringAddr(d, "az")},
-		},
+		Name: d.Get("instance_name").(string),
+	}
+
+	// These fields can be omitted when a template is specified
+	if _, ok := d.GetOk("az"); ok {
+		spec.Az = GetStringAddr(d, "az")
+	}
+	if _, ok := d.GetOk("instance_type"); ok {
+		spec.InstanceType = GetStringAddr(d, "instance_type")
+	}
+	if _, ok := d.GetOk("image_id"); ok {
+		spec.ImageId = GetStringAddr(d, "image_id")
+	}
+	if _, ok := d.GetOk("instance_template_id"); ok {
+		spec.InstanceTemplateId = GetStringAddr(d, "instance_template_id")
+	}
+	if _, ok := d.GetOk("availability_group_id"); ok {
+		spec.AgId = GetStringAddr(d, "availability_group_id")
 	}
 
 	if _, ok := d.GetOk("system_disk"); ok {
@@ -674,24 +1343,36 @@ func resourceJDCloudInstanceCreate(d *schema.ResourceData, m interface{}) error
 		spec.Password = GetStringAddr(d, "password")
 	}
 
-	if _, ok := d.GetOk("key_names"); ok {
-		spec.KeyNames = []string{d.Get("key_names").(string)}
+	if keyNames, ok := d.GetOk("key_names"); ok {
+		spec.KeyNames = typeSetToStringArray(keyNames.(*schema.Set))
 	}
 
-	if _, ok := d.GetOk("primary_ip"); ok {
-		spec.PrimaryNetworkInterface.NetworkInterface.PrimaryIpAddress = GetStringAddr(d, "primary_ip")
-	}
+	// Primary network interface is supplied by the template if subnet is not specified
+	if _, ok := d.GetOk("subnet_id"); ok {
 
-	if _, ok := d.GetOk("network_interface_name"); ok {
-		spec.PrimaryNetworkInterface.NetworkInterface.NetworkInterfaceName = GetStringAddr(d, "network_interface_name")
-	}
+		spec.PrimaryNetworkInterface = &vm.InstanceNetworkInterfaceAttachmentSpec{
+			NetworkInterface: &vpc.NetworkInterfaceSpec{SubnetId: d.Get("subnet_id").(string)},
+		}
 
-	if _, ok := d.GetOk("secondary_ip_count"); ok {
-		spec.PrimaryNetworkInterface.NetworkInterface.SecondaryIpCount = GetIntAddr(d, "secondary_ip_count")
-	}
+		if _, ok := d.GetOk("primary_ip"); ok {
+			spec.PrimaryNetworkInterface.NetworkInterface.PrimaryIpAddress = GetStringAddr(d, "primary_ip")
+		}
+
+		if _, ok := d.GetOk("network_interface_name"); ok {
+			spec.PrimaryNetworkInterface.NetworkInterface.NetworkInterfaceName = GetStringAddr(d, "network_interface_name")
+		}
+
+		if _, ok := d.GetOkExists("sanity_check"); ok {
+			spec.PrimaryNetworkInterface.NetworkInterface.SanityCheck = GetIntAddr(d, "sanity_check")
+		}
 
-	if _, ok := d.GetOk("security_group_ids"); ok {
-		spec.PrimaryNetworkInterface.NetworkInterface.SecurityGroups = typeSetToStringArray(d.Get("security_group_ids").(*schema.Set))
+		if _, ok := d.GetOk("secondary_ip_count"); ok {
+			spec.PrimaryNetworkInterface.NetworkInterface.SecondaryIpCount = GetIntAddr(d, "secondary_ip_count")
+		}
+
+		if _, ok := d.GetOk("security_group_ids"); ok {
+			spec.PrimaryNetworkInterface.NetworkInterface.SecurityGroups = typeSetToStringArray(d.Get("security_group_ids").(*schema.Set))
+		}
 	}
 
 	if v, ok := d.GetOk("elastic_ip_bandwidth_mbps"); ok {
@@ -708,12 +1389,21 @@ func resourceJDCloudInstanceCreate(d *schema.ResourceData, m interface{}) error
 		spec.ElasticIp.Provider = v.(string)
 	}
 
+	// Elastic ip follows the charge of instance, unless it is specified
+	spec.Charge = typeListToChargeSpec(d.Get("charge").([]interface{}))
+	if spec.ElasticIp != nil {
+		spec.ElasticIp.ChargeSpec = typeListToChargeSpec(d.Get("elastic_ip_charge").([]interface{}))
+	}
+
 	req := apis.NewCreateInstancesRequest(config.Region, &spec)
 	req.SetMaxCount(MAX_VM_COUNT)
 
+	// Retrying with the same token returns the instance created by a request whose response was lost
+	req.SetClientToken(clientTokenDefault())
+
 	// Just send a request here
 	instanceId := ""
-	err := resource.Retry(5*time.Minute, func() *resource.RetryError {
+	err := resource.Retry(d.Timeout(schema.TimeoutCreate), func() *resource.RetryError {
 
 		resp, err := vmClient.CreateInstances(req)
 
@@ -733,12 +1423,19 @@ func resourceJDCloudInstanceCreate(d *schema.ResourceData, m interface{}) error
 	}
 
 	// Waiting until VMs are ready
-	err = instanceStatusWaiter(d, m, instanceId, []string{VM_PENDING, VM_STARTING}, []string{VM_RUNNING})
+	err = instanceStatusWaiter(d, m, instanceId, []string{VM_PENDING, VM_STARTING}, []string{VM_RUNNING}, d.Timeout(schema.TimeoutCreate))
 	if err != nil {
 		return err
 	}
 
 	d.SetId(instanceId)
+
+	// Instances are always launched, stop it if required
+	if d.Get("instance_state").(string) == VM_STOPPED {
+		if err := ensureVmStopped(d, m, d.Id(), d.Timeout(schema.TimeoutCreate)); err != nil {
+			return err
+		}
+	}
 	return resourceJDCloudInstanceRead(d, m)
 }
 
@@ -764,9 +1461,34 @@ func resourceJDCloudInstanceRead(d *schema.ResourceData, m interface{}) error {
 	d.Set("primary_ip", vmInstanceDetail.Result.Instance.PrimaryNetworkInterface.NetworkInterface.PrimaryIp)
 	d.Set("elastic_ip", vmInstanceDetail.Result.Instance.ElasticIpAddress)
 	d.Set("az", vmInstanceDetail.Result.Instance.Az)
+	d.Set("availability_group_id", vmInstanceDetail.Result.Instance.Ag.Id)
+	d.Set("charge", chargeToTypeList(vmInstanceDetail.Result.Instance.Charge, d.Get("charge").([]interface{})))
+	d.Set("tags", tagsToMap(vmInstanceDetail.Result.Instance.Tags))
+
+	if eipId := vmInstanceDetail.Result.Instance.ElasticIpId; eipId != "" {
+		eip, err := queryElasticIp(m, eipId)
+		if err != nil {
+			return err
+		}
+		d.Set("elastic_ip_charge", chargeToTypeList(eip.Charge, d.Get("elastic_ip_charge").([]interface{})))
+	}
 	d.Set("key_names", vmInstanceDetail.Result.Instance.KeyNames)
 
-	if errSet := d.Set("security_group_ids", vmInstanceDetail.Result.Instance.PrimaryNetworkInterface.NetworkInterface.SecurityGroups); err != nil {
+	// Transient status are not reported, otherwise a drift will be shown during starting/stopping
+	if status := vmInstanceDetail.Result.Instance.Status; status == VM_RUNNING || status == VM_STOPPED {
+		d.Set("instance_state", status)
+	}
+
+	primaryInterface := vmInstanceDetail.Result.Instance.PrimaryNetworkInterface.NetworkInterface
+	d.Set("network_interface_id", primaryInterface.NetworkInterfaceId)
+	d.Set("mac_address", primaryInterface.MacAddress)
+	d.Set("sanity_check", primaryInterface.SanityCheck)
+
+	sgIds := []string{}
+	for _, sg := range primaryInterface.SecurityGroups {
+		sgIds = append(sgIds, sg.GroupId)
+	}
+	if errSet := d.Set("security_group_ids", sgIds); errSet != nil {
 		return fmt.Errorf("[ERROR] Failed in setting Sg Id LIST, reasons:%s", errSet.Error())
 	}
 
@@ -787,6 +1509,10 @@ func resourceJDCloudInstanceRead(d *schema.ResourceData, m interface{}) error {
 
 func resourceJDCloudInstanceUpdate(d *schema.ResourceData, m interface{}) error {
 
+	// Modifying data disks and network interfaces conflicts with attachments on this instance
+	lockParent(m, d.Id())
+	defer unlockParent(m, d.Id())
+
 	d.Partial(true)
 	defer d.Partial(false)
 	config := m.(*JDCloudConfig)
@@ -811,13 +1537,91 @@ func resourceJDCloudInstanceUpdate(d *schema.ResourceData, m interface{}) error
 		d.SetPartial("description")
 	}
 
-	if d.HasChange("password") {
-		// Stop VM
-		if err := StopVmInstance(d, m, d.Id()); err != nil {
-			return fmt.Errorf("stop instance got error:%s", err)
+	if d.HasChange("security_group_ids") || d.HasChange("network_interface_name") {
+
+		req := vpcApis.NewModifyNetworkInterfaceRequest(config.Region, d.Get("network_interface_id").(string))
+		req.SecurityGroups = typeSetToStringArray(d.Get("security_group_ids").(*schema.Set))
+		if _, ok := d.GetOk("network_interface_name"); ok {
+			req.NetworkInterfaceName = GetStringAddr(d, "network_interface_name")
+		}
+		err := resource.Retry(time.Minute, func() *resource.RetryError {
+
+			resp, err := vpcClient.NewVpcClient(config.Credential).ModifyNetworkInterface(req)
+
+			if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+				return nil
+			}
+
+			if connectionError(err) {
+				return resource.RetryableError(formatConnectionErrorMessage())
+			} else {
+				return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		d.SetPartial("security_group_ids")
+		d.SetPartial("network_interface_name")
+	}
+
+	if d.HasChange("data_disk") {
+		if err := updateInstanceDataDisks(d, m, d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return err
+		}
+		d.SetPartial("data_disk")
+	}
+
+	rebuild := d.HasChange("image_id") && d.Get("rebuild_on_image_change").(bool)
+
+	if d.HasChange("instance_type") || rebuild {
+
+		resp, err := QueryInstanceDetail(d, m, d.Id())
+		if err != nil {
+			return err
+		}
+		previousStatus := resp.Result.Instance.Status
+
+		// Resizing and rebuilding are available on stopped instances only
+		if err := ensureVmStopped(d, m, d.Id(), d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return err
+		}
+
+		// Password and key pair are reset together with the OS
+		if rebuild {
+			if err := rebuildVmInstance(d, m, d.Get("image_id").(string), d.Timeout(schema.TimeoutUpdate)); err != nil {
+				return err
+			}
+			d.SetPartial("image_id")
+			d.SetPartial("password")
+			d.SetPartial("key_names")
+		}
+
+		if d.HasChange("instance_type") {
+			if err := ensureVmStopped(d, m, d.Id(), d.Timeout(schema.TimeoutUpdate)); err != nil {
+				return err
+			}
+			if err := resizeVmInstance(d, m, d.Get("instance_type").(string), d.Timeout(schema.TimeoutUpdate)); err != nil {
+				return err
+			}
+			d.SetPartial("instance_type")
+		}
+
+		// Restore its previous power state, unless another one is expected
+		expectedStatus := previousStatus
+		if d.HasChange("instance_state") {
+			expectedStatus = d.Get("instance_state").(string)
+		}
+		if err := setVmPowerState(d, m, expectedStatus, d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return err
 		}
-		if err := instanceStatusWaiter(d, m, d.Id(), []string{VM_RUNNING, VM_STOPPING}, []string{VM_STOPPED, VM_STOPPED_2}); err != nil {
-			return fmt.Errorf("stop instance got error(2):%s", err)
+	}
+
+	if d.HasChange("password") && !rebuild {
+		// Stop VM
+		if err := ensureVmStopped(d, m, d.Id(), d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return err
 		}
 
 		//  Modify password
@@ -834,26 +1638,40 @@ func resourceJDCloudInstanceUpdate(d *schema.ResourceData, m interface{}) error
 			return err
 		}
 
-		// Then start it
-		if err := StartVmInstance(d, m); err != nil {
-			return fmt.Errorf("start instance got error:%s", err)
+		// Then start it, unless it is expected to be stopped
+		if err := setVmPowerState(d, m, d.Get("instance_state").(string), d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return err
 		}
 		d.SetPartial("password")
 	}
 
+	if d.HasChange("instance_state") {
+		if err := setVmPowerState(d, m, d.Get("instance_state").(string), d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return err
+		}
+		d.SetPartial("instance_state")
+	}
+
 	return resourceJDCloudInstanceRead(d, m)
 }
 
 func resourceJDCloudInstanceDelete(d *schema.ResourceData, m interface{}) error {
 
-	// Stop VM
-	err := StopVmInstance(d, m, d.Id())
+	if err := verifyDeletionProtection(d); err != nil {
+		return err
+	}
+
+	// Prepaid instances can not be deleted before they expire
+	resp, err := QueryInstanceDetail(d, m, d.Id())
 	if err != nil {
-		return fmt.Errorf("stop instance got error:%s", err)
+		return err
+	}
+	if proceed, err := prepaidDestroyAllowed(d, m, resp.Result.Instance.Charge.ChargeMode); !proceed {
+		return err
 	}
 
-	// Wait until stopped
-	err = instanceStatusWaiter(d, m, d.Id(), []string{VM_RUNNING, VM_STOPPING}, []string{VM_STOPPED, VM_STOPPED_2})
+	// Stop VM unless it is stopped already, e.g. instance_state = "stopped"
+	err = ensureVmStopped(d, m, d.Id(), d.Timeout(schema.TimeoutDelete))
 	if err != nil {
 		return err
 	}
@@ -865,7 +1683,7 @@ func resourceJDCloudInstanceDelete(d *schema.ResourceData, m interface{}) error
 	}
 
 	// Wait until deleted
-	err = instanceStatusWaiter(d, m, d.Id(), []string{VM_RUNNING, VM_STOPPING, VM_DELETING}, []string{VM_DELETED})
+	err = instanceStatusWaiter(d, m, d.Id(), []string{VM_RUNNING, VM_STOPPING, VM_DELETING}, []string{VM_DELETED}, d.Timeout(schema.TimeoutDelete))
 	if err != nil {
 		return err
 	}
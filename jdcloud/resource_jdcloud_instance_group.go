@@ -0,0 +1,406 @@
+package jdcloud
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/jdcloud-api/jdcloud-sdk-go/services/vm/apis"
+	"github.com/jdcloud-api/jdcloud-sdk-go/services/vm/client"
+	vm "github.com/jdcloud-api/jdcloud-sdk-go/services/vm/models"
+	vpc "github.com/jdcloud-api/jdcloud-sdk-go/services/vpc/models"
+	"time"
+)
+
+/*
+	An instance group is a set of identical instances, launched by CreateInstances with MaxCount.
+	It is not a concept of JDCloud, hence the ID is generated locally. Members are tracked
+	in instance_ids, following the order they were created in. Scaling in removes the newest ones.
+
+	"count" is a reserved name in Terraform, size of this group is given by instance_count
+*/
+
+//----------------------------------------------------------------------------------- OTHERS
+
+// Level 0 -> Build the spec shared by all members
+func instanceGroupSpec(d *schema.ResourceData) vm.InstanceSpec {
+
+	spec := vm.InstanceSpec{
+		Name: d.Get("name_prefix").(string),
+	}
+
+	// These fields can be omitted when a template is specified
+	if _, ok := d.GetOk("az"); ok {
+		spec.Az = GetStringAddr(d, "az")
+	}
+	if _, ok := d.GetOk("instance_type"); ok {
+		spec.InstanceType = GetStringAddr(d, "instance_type")
+	}
+	if _, ok := d.GetOk("image_id"); ok {
+		spec.ImageId = GetStringAddr(d, "image_id")
+	}
+	if _, ok := d.GetOk("instance_template_id"); ok {
+		spec.InstanceTemplateId = GetStringAddr(d, "instance_template_id")
+	}
+	if _, ok := d.GetOk("description"); ok {
+		spec.Description = GetStringAddr(d, "description")
+	}
+	if _, ok := d.GetOk("password"); ok {
+		spec.Password = GetStringAddr(d, "password")
+	}
+	if keyNames, ok := d.GetOk("key_names"); ok {
+		spec.KeyNames = typeSetToStringArray(keyNames.(*schema.Set))
+	}
+
+	if _, ok := d.GetOk("system_disk"); ok {
+		spec.SystemDisk = &(typeListToDiskList(d.Get("system_disk").([]interface{}))[0])
+	}
+	if _, ok := d.GetOk("data_disk"); ok {
+		spec.DataDisks = diskListTypeCloud(typeListToDiskList(d.Get("data_disk").([]interface{})))
+	}
+
+	// Primary network interface is supplied by the template if subnet is not specified
+	if _, ok := d.GetOk("subnet_id"); ok {
+		spec.PrimaryNetworkInterface = &vm.InstanceNetworkInterfaceAttachmentSpec{
+			NetworkInterface: &vpc.NetworkInterfaceSpec{SubnetId: d.Get("subnet_id").(string)},
+		}
+		if sgs, ok := d.GetOk("security_group_ids"); ok {
+			spec.PrimaryNetworkInterface.NetworkInterface.SecurityGroups = typeSetToStringArray(sgs.(*schema.Set))
+		}
+	}
+
+	if v, ok := d.GetOk("elastic_ip_bandwidth_mbps"); ok {
+		spec.ElasticIp = &vpc.ElasticIpSpec{
+			BandwidthMbps: v.(int),
+			Provider:      d.Get("elastic_ip_provider").(string),
+			ChargeSpec:    typeListToChargeSpec(d.Get("elastic_ip_charge").([]interface{})),
+		}
+	}
+
+	spec.Charge = typeListToChargeSpec(d.Get("charge").([]interface{}))
+	return spec
+}
+
+// Level 0 -> Launch some members in a single request
+func createInstanceGroupMembers(d *schema.ResourceData, m interface{}, count int, timeout time.Duration) (instanceIds []string, err error) {
+
+	config := m.(*JDCloudConfig)
+	vmClient := client.NewVmClient(config.Credential)
+	vmClient.SetLogger(vmLogger{})
+
+	spec := instanceGroupSpec(d)
+	req := apis.NewCreateInstancesRequest(config.Region, &spec)
+	req.SetMaxCount(count)
+
+	// Retrying with the same token returns the instances created by a request whose response was lost
+	req.SetClientToken(clientTokenDefault())
+
+	err = resource.Retry(timeout, func() *resource.RetryError {
+
+		resp, err := vmClient.CreateInstances(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			instanceIds = resp.Result.InstanceIds
+			return nil
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+	return
+}
+
+// Level 2 -> Launch some members and wait for them. Members are recorded and kept in partial state before waiting,
+// so that they are still tracked if some of them failed to start
+func scaleOutInstanceGroup(d *schema.ResourceData, m interface{}, count int, timeout time.Duration) error {
+
+	deadline := time.Now().Add(timeout)
+
+	newIds, err := createInstanceGroupMembers(d, m, count, timeout)
+	if err != nil {
+		return err
+	}
+
+	instanceIds := append(typeListToStringArray(d.Get("instance_ids").([]interface{})), newIds...)
+	if err := d.Set("instance_ids", instanceIds); err != nil {
+		return err
+	}
+	d.SetPartial("instance_ids")
+
+	return waitForInstancesRunning(d, m, newIds, time.Until(deadline))
+}
+
+func typeListToStringArray(l []interface{}) []string {
+	s := make([]string, 0, len(l))
+	for _, item := range l {
+		s = append(s, item.(string))
+	}
+	return s
+}
+
+//----------------------------------------------------------------------------------- RESOURCE
+
+func resourceJDCloudInstanceGroup() *schema.Resource {
+
+	return &schema.Resource{
+		Create: resourceJDCloudInstanceGroupCreate,
+		Read:   resourceJDCloudInstanceGroupRead,
+		Update: resourceJDCloudInstanceGroupUpdate,
+		Delete: resourceJDCloudInstanceGroupDelete,
+
+		CustomizeDiff: resourceJDCloudInstanceGroupCustomizeDiff,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(VM_TIMEOUT * time.Second),
+			Update: schema.DefaultTimeout(VM_TIMEOUT * time.Second),
+			Delete: schema.DefaultTimeout(VM_TIMEOUT * time.Second),
+		},
+
+		// All members share the same spec, changing it leads to a new group
+		Schema: map[string]*schema.Schema{
+			"name_prefix": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"instance_count": {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ValidateFunc: validateIntRange(1, MAX_VM_BATCH),
+			},
+			"az": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"instance_type": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"image_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"subnet_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			// Fields above are supplied by the template if not specified
+			"instance_template_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"password": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+				ForceNew:  true,
+			},
+			"key_names": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				ForceNew: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"security_group_ids": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				ForceNew: true,
+				MinItems: 1,
+				MaxItems: MAX_SECURITY_GROUP_COUNT,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"elastic_ip_bandwidth_mbps": {
+				Type:     schema.TypeInt,
+				Optional: true,
+				ForceNew: true,
+			},
+			"elastic_ip_provider": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"charge":            chargeSchema(CHARGE_PREPAID_BY_DURATION, CHARGE_POSTPAID_BY_DURATION),
+			"elastic_ip_charge": chargeSchema(CHARGE_PREPAID_BY_DURATION, CHARGE_POSTPAID_BY_USAGE, CHARGE_POSTPAID_BY_DURATION),
+
+			"prepaid_on_destroy":  prepaidOnDestroySchema(),
+			"deletion_protection": deletionProtectionSchema(),
+
+			"system_disk": {
+				Type:     schema.TypeList,
+				Optional: true,
+				ForceNew: true,
+				MinItems: 1,
+				MaxItems: MAX_SYSDISK_COUNT,
+				Elem:     instanceDiskSchema(true),
+			},
+			"data_disk": {
+				Type:     schema.TypeList,
+				Optional: true,
+				ForceNew: true,
+				MinItems: 1,
+				Elem:     instanceDiskSchema(true),
+			},
+
+			"instance_ids": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"private_ips": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"elastic_ips": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func resourceJDCloudInstanceGroupCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+
+	for _, key := range []string{"charge", "elastic_ip_charge"} {
+		if err := chargeCustomizeDiff(key, false)(d, m); err != nil {
+			return err
+		}
+	}
+	if d.Id() != "" {
+		return nil
+	}
+	return verifyInstanceTemplateFields(d)
+}
+
+func resourceJDCloudInstanceGroupCreate(d *schema.ResourceData, m interface{}) error {
+
+	instanceIds, err := createInstanceGroupMembers(d, m, d.Get("instance_count").(int), d.Timeout(schema.TimeoutCreate))
+	if err != nil {
+		return err
+	}
+
+	d.SetId(resource.PrefixedUniqueId("ig-"))
+	if err := d.Set("instance_ids", instanceIds); err != nil {
+		return err
+	}
+
+	if err := waitForInstancesRunning(d, m, instanceIds, d.Timeout(schema.TimeoutCreate)); err != nil {
+		return err
+	}
+	return resourceJDCloudInstanceGroupRead(d, m)
+}
+
+// Members deleted outside Terraform are dropped, instance_count then shows the drift
+func resourceJDCloudInstanceGroupRead(d *schema.ResourceData, m interface{}) error {
+
+	instanceIds := typeListToStringArray(d.Get("instance_ids").([]interface{}))
+	instances, err := queryInstances(m, instanceIds)
+	if err != nil {
+		return fmt.Errorf("[E] Failed in InstanceGroupRead/QueryInstances %v", err)
+	}
+
+	ids, privateIps, elasticIps := []string{}, []string{}, []string{}
+	for _, id := range instanceIds {
+		item, ok := instances[id]
+		if !ok || item.(vm.Instance).Status == VM_DELETED {
+			continue
+		}
+		instance := item.(vm.Instance)
+		ids = append(ids, instance.InstanceId)
+		privateIps = append(privateIps, instance.PrivateIpAddress)
+		elasticIps = append(elasticIps, instance.ElasticIpAddress)
+	}
+
+	if len(ids) == 0 {
+		d.SetId("")
+		return nil
+	}
+
+	first := instances[ids[0]].(vm.Instance)
+	d.Set("charge", chargeToTypeList(first.Charge, d.Get("charge").([]interface{})))
+	d.Set("instance_count", len(ids))
+
+	if err := d.Set("instance_ids", ids); err != nil {
+		return fmt.Errorf("[ERROR] Failed in setting instance_ids, reasons:%s", err.Error())
+	}
+	if err := d.Set("private_ips", privateIps); err != nil {
+		return fmt.Errorf("[ERROR] Failed in setting private_ips, reasons:%s", err.Error())
+	}
+	if err := d.Set("elastic_ips", elasticIps); err != nil {
+		return fmt.Errorf("[ERROR] Failed in setting elastic_ips, reasons:%s", err.Error())
+	}
+	return nil
+}
+
+func resourceJDCloudInstanceGroupUpdate(d *schema.ResourceData, m interface{}) error {
+
+	if !d.HasChange("instance_count") {
+		return resourceJDCloudInstanceGroupRead(d, m)
+	}
+
+	d.Partial(true)
+
+	instanceIds := typeListToStringArray(d.Get("instance_ids").([]interface{}))
+	count := d.Get("instance_count").(int)
+
+	if count > len(instanceIds) {
+		if err := scaleOutInstanceGroup(d, m, count-len(instanceIds), d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return err
+		}
+	}
+
+	if count < len(instanceIds) {
+
+		// Prepaid members can not be deleted before they expire
+		if c := typeListToChargeSpec(d.Get("charge").([]interface{})); c != nil && *c.ChargeMode == CHARGE_PREPAID_BY_DURATION {
+			return fmt.Errorf("[ERROR] Members of %s are prepaid and can not be deleted before they expire", d.Id())
+		}
+		if err := deleteInstances(d, m, instanceIds[count:], d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return err
+		}
+		if err := d.Set("instance_ids", instanceIds[:count]); err != nil {
+			return err
+		}
+		d.SetPartial("instance_ids")
+	}
+
+	d.SetPartial("instance_count")
+	d.Partial(false)
+	return resourceJDCloudInstanceGroupRead(d, m)
+}
+
+func resourceJDCloudInstanceGroupDelete(d *schema.ResourceData, m interface{}) error {
+
+	if err := verifyDeletionProtection(d); err != nil {
+		return err
+	}
+
+	chargeMode := ""
+	if c := typeListToChargeSpec(d.Get("charge").([]interface{})); c != nil {
+		chargeMode = *c.ChargeMode
+	}
+	if proceed, err := prepaidDestroyAllowed(d, m, chargeMode); !proceed {
+		return err
+	}
+
+	instanceIds := typeListToStringArray(d.Get("instance_ids").([]interface{}))
+	if err := deleteInstances(d, m, instanceIds, d.Timeout(schema.TimeoutDelete)); err != nil {
+		return err
+	}
+
+	d.SetId("")
+	return nil
+}
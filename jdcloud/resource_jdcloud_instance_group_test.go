@@ -0,0 +1,121 @@
+package jdcloud
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	vm "github.com/jdcloud-api/jdcloud-sdk-go/services/vm/models"
+	"strconv"
+	"testing"
+)
+
+/*
+	TestCase : 1. Launch 2 members in one request, scale out to 3, then scale in to 1
+*/
+
+const testAccInstanceGroupTemplate = `
+resource "jdcloud_instance_group" "group" {
+  name_prefix    = "terraform-group"
+  instance_count = %d
+
+  az                 = "cn-north-1c"
+  instance_type      = "c.n1.large"
+  image_id           = "%s"
+  subnet_id          = "%s"
+  security_group_ids = ["%s"]
+
+  system_disk {
+    disk_category = "local"
+    device_name   = "vda"
+    disk_size_gb  = 40
+  }
+}
+`
+
+func generateInstanceGroupConfig(count int) string {
+	return fmt.Sprintf(testAccInstanceGroupTemplate, count, packer_image, packer_subnet, packer_sg)
+}
+
+func TestAccJDCloudInstanceGroup_basic(t *testing.T) {
+
+	var instanceIds []string
+
+	resource.Test(t, resource.TestCase{
+
+		IDRefreshName: "jdcloud_instance_group.group",
+		PreCheck:      func() { testAccPreCheck(t) },
+		Providers:     testAccProviders,
+		CheckDestroy:  testAccIfInstanceGroupDestroyed(&instanceIds),
+		Steps: []resource.TestStep{
+			{
+				Config: generateInstanceGroupConfig(2),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfInstanceGroupExists("jdcloud_instance_group.group", 2, &instanceIds),
+					resource.TestCheckResourceAttr("jdcloud_instance_group.group", "private_ips.#", "2"),
+				),
+			},
+			{
+				Config: generateInstanceGroupConfig(3),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfInstanceGroupExists("jdcloud_instance_group.group", 3, &instanceIds),
+					resource.TestCheckResourceAttr("jdcloud_instance_group.group", "private_ips.#", "3"),
+				),
+			},
+			{
+				Config: generateInstanceGroupConfig(1),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfInstanceGroupExists("jdcloud_instance_group.group", 1, &instanceIds),
+					resource.TestCheckResourceAttr("jdcloud_instance_group.group", "private_ips.#", "1"),
+				),
+			},
+		},
+	})
+}
+
+func testAccIfInstanceGroupExists(name string, count int, instanceIds *[]string) resource.TestCheckFunc {
+
+	return func(stateInfo *terraform.State) error {
+
+		info, ok := stateInfo.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("[ERROR] testAccIfInstanceGroupExists failed, resource %s not found in terraform.State", name)
+		}
+		if info.Primary.ID == "" {
+			return fmt.Errorf("[ERROR] testAccIfInstanceGroupExists failed, group is created but ID not set")
+		}
+
+		n, _ := strconv.Atoi(info.Primary.Attributes["instance_ids.#"])
+		ids := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			ids = append(ids, info.Primary.Attributes[fmt.Sprintf("instance_ids.%d", i)])
+		}
+
+		instances, err := queryInstances(testAccProvider.Meta(), ids)
+		if err != nil {
+			return err
+		}
+		if len(instances) != count {
+			return fmt.Errorf("[ERROR] testAccIfInstanceGroupExists failed, expecting %d members, got %d", count, len(instances))
+		}
+
+		*instanceIds = ids
+		return nil
+	}
+}
+
+func testAccIfInstanceGroupDestroyed(instanceIds *[]string) resource.TestCheckFunc {
+
+	return func(stateInfo *terraform.State) error {
+
+		instances, err := queryInstances(testAccProvider.Meta(), *instanceIds)
+		if err != nil {
+			return err
+		}
+		for id, item := range instances {
+			if item.(vm.Instance).Status != VM_DELETED {
+				return fmt.Errorf("[ERROR] testAccIfInstanceGroupDestroyed failed, %s still exists", id)
+			}
+		}
+		return nil
+	}
+}
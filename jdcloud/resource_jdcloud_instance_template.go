@@ -58,12 +58,14 @@ func resourceJDCloudInstanceTemplate() *schema.Resource {
 		},
 	}
 
-	return &schema.Resource{
+	r := &schema.Resource{
 		Create: resourceJDCloudInstanceTemplateCreate,
 		Read:   resourceJDCloudInstanceTemplateRead,
 		Update: resourceJDCloudInstanceTemplateUpdate,
 		Delete: resourceJDCloudInstanceTemplateDelete,
 
+		// Only template_name and description can be modified, templates are replaced otherwise.
+		// Availability groups switch to the new template in place, so create_before_destroy works with them
 		Schema: map[string]*schema.Schema{
 			"template_name": &schema.Schema{
 				Type:     schema.TypeString,
@@ -72,10 +74,12 @@ func resourceJDCloudInstanceTemplate() *schema.Resource {
 			"instance_type": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
+				ForceNew: true,
 			},
 			"image_id": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
+				ForceNew: true,
 			},
 			"description": &schema.Schema{
 				Type:     schema.TypeString,
@@ -84,36 +88,45 @@ func resourceJDCloudInstanceTemplate() *schema.Resource {
 			"password": &schema.Schema{
 				Type:          schema.TypeString,
 				Optional:      true,
+				ForceNew:      true,
 				Sensitive:     true,
 				ConflictsWith: []string{"key_names"},
 			},
 			"key_names": &schema.Schema{
-				Type:          schema.TypeString,
+				Type:          schema.TypeSet,
 				Optional:      true,
 				ForceNew:      true,
 				ConflictsWith: []string{"password"},
+				Elem:          &schema.Schema{Type: schema.TypeString},
 			},
 			"bandwidth": &schema.Schema{
 				Type:     schema.TypeInt,
 				Optional: true,
+				ForceNew: true,
 			},
 			"ip_service_provider": &schema.Schema{
 				Type:     schema.TypeString,
 				Optional: true,
+				ForceNew: true,
 			},
+			// Charge of elastic ip in a template is billed by bandwidth or traffic, not by duration
 			"charge_mode": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validateStringCandidates("bandwith", "flow"),
 			},
 
 			"subnet_id": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
+				ForceNew: true,
 			},
 			"security_group_ids": &schema.Schema{
 				Type:     schema.TypeSet,
 				MinItems: 1,
 				Required: true,
+				ForceNew: true,
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
 				},
@@ -129,10 +142,38 @@ func resourceJDCloudInstanceTemplate() *schema.Resource {
 			"data_disks": &schema.Schema{
 				Type:     schema.TypeList,
 				Optional: true,
+				ForceNew: true,
 				Elem:     diskSchema,
 			},
 		},
 	}
+
+	// key_names used to be a single string
+	r.SchemaVersion = 1
+	r.StateUpgraders = []schema.StateUpgrader{
+		{
+			Version: 0,
+			Type:    resourceJDCloudInstanceTemplateV0(r.Schema).CoreConfigSchema().ImpliedType(),
+			Upgrade: stringToListStateUpgradeFunc("key_names"),
+		},
+	}
+	return r
+}
+
+// Schema of version 0, where key_names is a string
+func resourceJDCloudInstanceTemplateV0(current map[string]*schema.Schema) *schema.Resource {
+
+	s := make(map[string]*schema.Schema, len(current))
+	for k, v := range current {
+		s[k] = v
+	}
+	s["key_names"] = &schema.Schema{
+		Type:          schema.TypeString,
+		Optional:      true,
+		ForceNew:      true,
+		ConflictsWith: []string{"password"},
+	}
+	return &schema.Resource{Schema: s}
 }
 
 func resourceJDCloudInstanceTemplateCreate(d *schema.ResourceData, m interface{}) error {
@@ -170,8 +211,8 @@ func resourceJDCloudInstanceTemplateCreate(d *schema.ResourceData, m interface{}
 	if _, ok := d.GetOk("system_disk"); ok {
 		templateSpec.SystemDisk = typeListToDiskTemplateList(d.Get("system_disk").([]interface{}))[0]
 	}
-	if _, ok := d.GetOk("key_names"); ok {
-		templateSpec.KeyNames = []string{d.Get("key_names").(string)}
+	if keyNames, ok := d.GetOk("key_names"); ok {
+		templateSpec.KeyNames = typeSetToStringArray(keyNames.(*schema.Set))
 	}
 	if _, ok := d.GetOk("data_disks"); ok {
 		templateSpec.DataDisks = typeListToDiskTemplateList(d.Get("data_disks").([]interface{}))
@@ -226,7 +267,7 @@ func resourceJDCloudInstanceTemplateRead(d *schema.ResourceData, m interface{})
 			}
 
 			if len(resp.Result.InstanceTemplate.InstanceTemplateData.KeyNames) > 0 {
-				d.Set("key_names", resp.Result.InstanceTemplate.InstanceTemplateData.KeyNames[0])
+				d.Set("key_names", resp.Result.InstanceTemplate.InstanceTemplateData.KeyNames)
 			}
 			sysDisk := typeListToDiskTemplateMap([]vm.InstanceTemplateDiskAttachment{resp.Result.InstanceTemplate.InstanceTemplateData.SystemDisk})
 			sysDisk[0]["disk_type"] = d.Get("system_disk.0.disk_type")
@@ -261,10 +302,10 @@ func resourceJDCloudInstanceTemplateRead(d *schema.ResourceData, m interface{})
 
 func resourceJDCloudInstanceTemplateUpdate(d *schema.ResourceData, m interface{}) error {
 
-	if d.HasChange("template_name") {
+	if d.HasChange("template_name") || d.HasChange("description") {
 		config := m.(*JDCloudConfig)
 		vmClient := client.NewVmClient(config.Credential)
-		req := apis.NewUpdateInstanceTemplateRequestWithAllParams(config.Region, d.Id(), nil, stringAddr(d.Get("template_name")))
+		req := apis.NewUpdateInstanceTemplateRequestWithAllParams(config.Region, d.Id(), stringAddr(d.Get("description")), stringAddr(d.Get("template_name")))
 
 		err := resource.Retry(2*time.Minute, func() *resource.RetryError {
 
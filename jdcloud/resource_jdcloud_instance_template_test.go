@@ -246,6 +246,71 @@ func TestAccJDCloudInstanceTemplate_Bandwidth(t *testing.T) {
 	})
 }
 
+// Description is modified in place, instance type replaces the template
+const TestAccInstanceTemplateReplace = `
+resource "jdcloud_instance_template" "instance_template_replace" {
+  template_name = "%s"
+  description = "%s"
+  instance_type = "%s"
+  image_id = "%s"
+  password = "DevOps2018"
+  subnet_id = "%s"
+  security_group_ids = ["%s"]
+  system_disk  {
+    disk_category = "local"
+  }
+}
+`
+
+func instanceTemplateReplace(name, description, instanceType string) string {
+	return fmt.Sprintf(TestAccInstanceTemplateReplace, name, description, instanceType, packer_image, packer_subnet, packer_sg)
+}
+
+func TestAccJDCloudInstanceTemplate_Replace(t *testing.T) {
+
+	var instanceTemplateId, previousId string
+	name := randomStringWithLength(10)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccIfTemplateDestroyed(&instanceTemplateId),
+		Steps: []resource.TestStep{
+			{
+				Config: instanceTemplateReplace(name, "first", "g.n2.medium"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfTemplateExists("jdcloud_instance_template.instance_template_replace", &previousId),
+				),
+			},
+			{
+				Config: instanceTemplateReplace(name, "second", "g.n2.medium"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfTemplateExists("jdcloud_instance_template.instance_template_replace", &instanceTemplateId),
+					testAccIfTemplateIdEquals(&instanceTemplateId, &previousId, true),
+					resource.TestCheckResourceAttr("jdcloud_instance_template.instance_template_replace", "description", "second"),
+				),
+			},
+			{
+				Config: instanceTemplateReplace(name, "second", "g.n2.large"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfTemplateExists("jdcloud_instance_template.instance_template_replace", &instanceTemplateId),
+					testAccIfTemplateIdEquals(&instanceTemplateId, &previousId, false),
+					resource.TestCheckResourceAttr("jdcloud_instance_template.instance_template_replace", "instance_type", "g.n2.large"),
+				),
+			},
+		},
+	})
+}
+
+func testAccIfTemplateIdEquals(current, previous *string, expected bool) resource.TestCheckFunc {
+	return func(stateInfo *terraform.State) error {
+		if (*current == *previous) != expected {
+			return fmt.Errorf("[ERROR] testAccIfTemplateIdEquals failed, current %s, previous %s, expecting equal: %t", *current, *previous, expected)
+		}
+		return nil
+	}
+}
+
 func testAccIfTemplateExists(templateName string, templateId *string) resource.TestCheckFunc {
 
 	return func(stateInfo *terraform.State) error {
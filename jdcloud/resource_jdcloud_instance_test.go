@@ -35,7 +35,7 @@ const testAccInstanceGeneral = `
 resource "jdcloud_instance" "terraform-normal-case" {
  az            = "cn-north-1c"
  instance_name = "%s"
- instance_type = "g.n2.medium"
+ instance_type = "%s"
  image_id      = "%s"
  password      = "%s"
  description   = "%s"
@@ -57,8 +57,8 @@ resource "jdcloud_instance" "terraform-normal-case" {
 }
 `
 
-func generateInstanceConfig(instanceName, password, description string) string {
-	return fmt.Sprintf(testAccInstanceGeneral, instanceName, packer_image, password, description, packer_subnet, packer_sg)
+func generateInstanceConfig(instanceName, instanceType, password, description string) string {
+	return fmt.Sprintf(testAccInstanceGeneral, instanceName, instanceType, packer_image, password, description, packer_subnet, packer_sg)
 }
 
 func TestAccJDCloudInstance_basic(t *testing.T) {
@@ -76,7 +76,7 @@ func TestAccJDCloudInstance_basic(t *testing.T) {
 		CheckDestroy:  testAccDiskInstanceDestroy("jdcloud_instance.terraform-normal-case", &instanceId),
 		Steps: []resource.TestStep{
 			{
-				Config: generateInstanceConfig(name1, "DevOps2018~", des1),
+				Config: generateInstanceConfig(name1, "g.n2.medium", "DevOps2018~", des1),
 				Check: resource.ComposeTestCheckFunc(
 
 					// Assigned values
@@ -111,7 +111,7 @@ func TestAccJDCloudInstance_basic(t *testing.T) {
 				),
 			},
 			{
-				Config: generateInstanceConfig(name2, "DevOps2018!", des2),
+				Config: generateInstanceConfig(name2, "g.n2.large", "DevOps2018!", des2),
 				Check: resource.ComposeTestCheckFunc(
 					testAccIfInstanceExists("jdcloud_instance.terraform-normal-case", &instanceId),
 					resource.TestCheckResourceAttr(
@@ -119,7 +119,7 @@ func TestAccJDCloudInstance_basic(t *testing.T) {
 					resource.TestCheckResourceAttr(
 						"jdcloud_instance.terraform-normal-case", "instance_name", name2),
 					resource.TestCheckResourceAttr(
-						"jdcloud_instance.terraform-normal-case", "instance_type", "g.n2.medium"),
+						"jdcloud_instance.terraform-normal-case", "instance_type", "g.n2.large"),
 					resource.TestCheckResourceAttr(
 						"jdcloud_instance.terraform-normal-case", "image_id", packer_image),
 					resource.TestCheckResourceAttr(
@@ -477,6 +477,19 @@ func TestAccJDCloudInstanceSG(t *testing.T) {
 					// These values not supposed to exists after resource_XYZ_Read
 					resource.TestCheckNoResourceAttr(
 						"jdcloud_instance.terraform-instance-sg", "data_disk"),
+					resource.TestCheckResourceAttrSet(
+						"jdcloud_instance.terraform-instance-sg", "network_interface_id"),
+					resource.TestCheckResourceAttrSet(
+						"jdcloud_instance.terraform-instance-sg", "mac_address"),
+				),
+			},
+			{
+				Config: instanceConfigSG(name1, packer_image, "DevOps2018~", des1, packer_subnet, fmt.Sprintf(`["%s"]`, packer_sg)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfInstanceExists(
+						"jdcloud_instance.terraform-instance-sg", &instanceId),
+					resource.TestCheckResourceAttr(
+						"jdcloud_instance.terraform-instance-sg", "security_group_ids.#", "1"),
 				),
 			},
 		},
@@ -578,6 +591,219 @@ func TestAccJDCloudInstancePW(t *testing.T) {
 	})
 }
 */
+
+// 8. [State] Stop and start an instance
+const testAccInstanceState = `
+resource "jdcloud_instance" "terraform-instance-state" {
+ az            = "cn-north-1c"
+ instance_name = "%s"
+ instance_type = "g.n2.medium"
+ image_id      = "%s"
+ password      = "DevOps2018~"
+
+ subnet_id              = "%s"
+ security_group_ids     = ["%s"]
+ instance_state         = "%s"
+
+ system_disk {
+   disk_category = "local"
+   auto_delete   = true
+   device_name   = "vda"
+   disk_size_gb =  40
+ }
+}
+`
+
+func instanceConfigState(instanceName, state string) string {
+	return fmt.Sprintf(testAccInstanceState, instanceName, packer_image, packer_subnet, packer_sg, state)
+}
+
+func TestAccJDCloudInstance_state(t *testing.T) {
+
+	var instanceId string
+	name := randomStringWithLength(10)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:      func() { testAccPreCheck(t) },
+		Providers:     testAccProviders,
+		IDRefreshName: "jdcloud_instance.terraform-instance-state",
+		CheckDestroy:  testAccDiskInstanceDestroy("jdcloud_instance.terraform-instance-state", &instanceId),
+		Steps: []resource.TestStep{
+			{
+				Config: instanceConfigState(name, "stopped"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfInstanceExists(
+						"jdcloud_instance.terraform-instance-state", &instanceId),
+					resource.TestCheckResourceAttr(
+						"jdcloud_instance.terraform-instance-state", "instance_state", "stopped"),
+				),
+			},
+			{
+				Config: instanceConfigState(name, "running"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfInstanceExists(
+						"jdcloud_instance.terraform-instance-state", &instanceId),
+					resource.TestCheckResourceAttr(
+						"jdcloud_instance.terraform-instance-state", "instance_state", "running"),
+				),
+			},
+		},
+	})
+}
+
+// 9. [Data-Disk] Add, modify and remove cloud data disks on an existing instance
+const testAccInstanceDataDisk = `
+resource "jdcloud_instance" "terraform-instance-data-disk" {
+ az            = "cn-north-1c"
+ instance_name = "%s"
+ instance_type = "g.n2.medium"
+ image_id      = "%s"
+ password      = "DevOps2018~"
+
+ subnet_id              = "%s"
+ security_group_ids     = ["%s"]
+
+ system_disk {
+   disk_category = "local"
+   auto_delete   = true
+   device_name   = "vda"
+   disk_size_gb =  40
+ }
+%s
+}
+`
+
+const testAccInstanceDataDiskItem = `
+ data_disk {
+   disk_category = "cloud"
+   disk_type     = "ssd"
+   disk_size_gb  = %d
+   device_name   = "%s"
+   auto_delete   = %t
+ }
+`
+
+func instanceConfigDataDisk(instanceName string, autoDelete bool, size int, devices ...string) string {
+	disks := ""
+	for _, device := range devices {
+		disks += fmt.Sprintf(testAccInstanceDataDiskItem, size, device, autoDelete)
+	}
+	return fmt.Sprintf(testAccInstanceDataDisk, instanceName, packer_image, packer_subnet, packer_sg, disks)
+}
+
+func TestAccJDCloudInstance_dataDisk(t *testing.T) {
+
+	var instanceId, diskId string
+	name := randomStringWithLength(10)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:      func() { testAccPreCheck(t) },
+		Providers:     testAccProviders,
+		IDRefreshName: "jdcloud_instance.terraform-instance-data-disk",
+		CheckDestroy:  testAccDiskInstanceDestroy("jdcloud_instance.terraform-instance-data-disk", &instanceId),
+		Steps: []resource.TestStep{
+			{
+				Config: instanceConfigDataDisk(name, true, 20, "vdb"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfInstanceExists(
+						"jdcloud_instance.terraform-instance-data-disk", &instanceId),
+					resource.TestCheckResourceAttr(
+						"jdcloud_instance.terraform-instance-data-disk", "data_disk.#", "1"),
+				),
+			},
+			{
+				Config: instanceConfigDataDisk(name, true, 20, "vdb", "vdc"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfInstanceExists(
+						"jdcloud_instance.terraform-instance-data-disk", &instanceId),
+					resource.TestCheckResourceAttr(
+						"jdcloud_instance.terraform-instance-data-disk", "data_disk.#", "2"),
+					resource.TestCheckResourceAttrSet(
+						"jdcloud_instance.terraform-instance-data-disk", "data_disk.1.disk_id"),
+				),
+			},
+			{
+				Config: instanceConfigDataDisk(name, false, 20, "vdc"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfInstanceExists(
+						"jdcloud_instance.terraform-instance-data-disk", &instanceId),
+					resource.TestCheckResourceAttr(
+						"jdcloud_instance.terraform-instance-data-disk", "data_disk.#", "1"),
+					resource.TestCheckResourceAttr(
+						"jdcloud_instance.terraform-instance-data-disk", "data_disk.0.device_name", "vdc"),
+					resource.TestCheckResourceAttr(
+						"jdcloud_instance.terraform-instance-data-disk", "data_disk.0.auto_delete", "false"),
+					testAccRecordAttr("jdcloud_instance.terraform-instance-data-disk", "data_disk.0.disk_id", &diskId),
+				),
+			},
+			{
+				// Growing a cloud disk extends it rather than replacing it
+				Config: instanceConfigDataDisk(name, false, 40, "vdc"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfInstanceExists(
+						"jdcloud_instance.terraform-instance-data-disk", &instanceId),
+					resource.TestCheckResourceAttr(
+						"jdcloud_instance.terraform-instance-data-disk", "data_disk.0.disk_size_gb", "40"),
+					resource.TestCheckResourceAttrPtr(
+						"jdcloud_instance.terraform-instance-data-disk", "data_disk.0.disk_id", &diskId),
+				),
+			},
+		},
+	})
+}
+
+func testAccRecordAttr(name, key string, value *string) resource.TestCheckFunc {
+
+	return func(stateInfo *terraform.State) error {
+
+		info, ok := stateInfo.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("[ERROR] testAccRecordAttr failed, resource %s not found in terraform.State", name)
+		}
+		*value = info.Primary.Attributes[key]
+		return nil
+	}
+}
+
+// 10. [Template] Create a vm from a template, into an availability group
+const testAccInstanceTemplateAg = `
+resource "jdcloud_instance" "terraform-instance-template" {
+ instance_name         = "%s"
+ instance_template_id  = "%s"
+ availability_group_id = "%s"
+}
+`
+
+func TestAccJDCloudInstance_template(t *testing.T) {
+
+	var instanceId string
+	name := randomStringWithLength(10)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:      func() { testAccPreCheck(t) },
+		Providers:     testAccProviders,
+		IDRefreshName: "jdcloud_instance.terraform-instance-template",
+		CheckDestroy:  testAccDiskInstanceDestroy("jdcloud_instance.terraform-instance-template", &instanceId),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccInstanceTemplateAg, name, packer_template, packer_ag),
+				Check: resource.ComposeTestCheckFunc(
+					testAccIfInstanceExists(
+						"jdcloud_instance.terraform-instance-template", &instanceId),
+					resource.TestCheckResourceAttr(
+						"jdcloud_instance.terraform-instance-template", "availability_group_id", packer_ag),
+					resource.TestCheckResourceAttrSet(
+						"jdcloud_instance.terraform-instance-template", "image_id"),
+					resource.TestCheckResourceAttrSet(
+						"jdcloud_instance.terraform-instance-template", "instance_type"),
+					resource.TestCheckResourceAttrSet(
+						"jdcloud_instance.terraform-instance-template", "subnet_id"),
+				),
+			},
+		},
+	})
+}
+
 // Currently, verification on disks is not available
 func testAccIfInstanceExists(resourceName string, instanceId *string) resource.TestCheckFunc {
 
@@ -17,6 +17,20 @@ func resourceJDCloudNetworkInterfaceAttach() *schema.Resource {
 		Create: resourceJDCloudNetworkInterfaceAttachCreate,
 		Read:   resourceJDCloudNetworkInterfaceAttachRead,
 		Delete: resourceJDCloudNetworkInterfaceAttachDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceJDCloudNetworkInterfaceAttachImport,
+		},
+
+		// Version 0 identifies an attachment by the request ID
+		// Version 1 identifies an attachment by "<instance_id>:<network_interface_id>"
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceJDCloudNetworkInterfaceAttachV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: compositeIdStateUpgradeFunc("instance_id", "network_interface_id"),
+			},
+		},
 
 		Schema: map[string]*schema.Schema{
 			"instance_id": &schema.Schema{
@@ -42,6 +56,9 @@ func resourceJDCloudNetworkInterfaceAttach() *schema.Resource {
 
 func resourceJDCloudNetworkInterfaceAttachCreate(d *schema.ResourceData, meta interface{}) error {
 
+	lockParent(meta, d.Get("instance_id").(string))
+	defer unlockParent(meta, d.Get("instance_id").(string))
+
 	config := meta.(*JDCloudConfig)
 	instanceID := d.Get("instance_id").(string)
 	networkInterfaceID := d.Get("network_interface_id").(string)
@@ -59,7 +76,7 @@ func resourceJDCloudNetworkInterfaceAttachCreate(d *schema.ResourceData, meta in
 		resp, err := vmClient.AttachNetworkInterface(req)
 
 		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
-			d.SetId(resp.RequestID)
+			d.SetId(buildCompositeId(instanceID, networkInterfaceID))
 			return nil
 		}
 		if connectionError(err) {
@@ -76,41 +93,47 @@ func resourceJDCloudNetworkInterfaceAttachCreate(d *schema.ResourceData, meta in
 
 func resourceJDCloudNetworkInterfaceAttachRead(d *schema.ResourceData, meta interface{}) error {
 
-	config := meta.(*JDCloudConfig)
+	instanceId := d.Get("instance_id").(string)
 	networkInterfaceId := d.Get("network_interface_id").(string)
 
-	vpcClient := vpc.NewVpcClient(config.Credential)
-	req := vpcApis.NewDescribeNetworkInterfaceRequest(config.Region, networkInterfaceId)
-
-	return resource.Retry(time.Minute, func() *resource.RetryError {
-
-		resp, err := vpcClient.DescribeNetworkInterface(req)
-
-		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
-
-			d.Set("network_interface_id", resp.Result.NetworkInterface.NetworkInterfaceId)
-			d.Set("instance_id", resp.Result.NetworkInterface.InstanceId)
+	resp, err := QueryInstanceDetail(d, meta, instanceId)
+	if err != nil {
+		return err
+	}
 
+	for _, ni := range resp.Result.Instance.SecondaryNetworkInterfaces {
+		if ni.NetworkInterface.NetworkInterfaceId == networkInterfaceId {
+			d.Set("instance_id", instanceId)
+			d.Set("network_interface_id", networkInterfaceId)
+			d.Set("auto_delete", ni.AutoDelete)
 			return nil
 		}
+	}
 
-		if resp.Result.NetworkInterface.InstanceId == "" {
-			log.Printf("Resource not found, probably have been deleted")
-			d.SetId("")
-			return nil
-		}
+	// Instance has gone or this interface has been detached out of band
+	log.Printf("Resource not found, probably have been deleted")
+	d.SetId("")
+	return nil
+}
 
-		if connectionError(err) {
-			return resource.RetryableError(formatConnectionErrorMessage())
-		} else {
-			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
-		}
-	})
+func resourceJDCloudNetworkInterfaceAttachImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+
+	parts, err := parseCompositeId(d.Id(), 2, "<instance_id>:<network_interface_id>")
+	if err != nil {
+		return nil, err
+	}
+
+	d.Set("instance_id", parts[0])
+	d.Set("network_interface_id", parts[1])
+	return []*schema.ResourceData{d}, nil
 }
 
 // Both of their ids will be attached immediately after the request has been sent.
 func resourceJDCloudNetworkInterfaceAttachDelete(d *schema.ResourceData, meta interface{}) error {
 
+	lockParent(meta, d.Get("instance_id").(string))
+	defer unlockParent(meta, d.Get("instance_id").(string))
+
 	config := meta.(*JDCloudConfig)
 	instanceId := d.Get("instance_id").(string)
 	networkInterfaceId := d.Get("network_interface_id").(string)
@@ -162,6 +185,28 @@ func resourceJDCloudNetworkInterfaceAttachDelete(d *schema.ResourceData, meta in
 	})
 }
 
+func resourceJDCloudNetworkInterfaceAttachV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"instance_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"network_interface_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"auto_delete": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				ForceNew: true,
+			},
+		},
+	}
+}
+
 // Discarded - Expected to be removed in the future
 
 func waitForCreatingComplete(d *schema.ResourceData, meta interface{}) error {
@@ -40,6 +40,11 @@ func TestAccJDCloudNetworkInterfaceAttachment_basic(t *testing.T) {
 					resource.TestCheckNoResourceAttr("jdcloud_network_interface_attachment.attachment-TEST-1", "auto_delete"),
 				),
 			},
+			{
+				ResourceName:      "jdcloud_network_interface_attachment.attachment-TEST-1",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 		},
 	})
 
@@ -91,6 +91,9 @@ func resourceJDCloudNetworkSecurityGroupRules() *schema.Resource {
 		Read:   resourceJDCloudNetworkSecurityGroupRulesRead,
 		Update: resourceJDCloudNetworkSecurityGroupRulesUpdate,
 		Delete: resourceJDCloudNetworkSecurityGroupRulesDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
 
 		Schema: map[string]*schema.Schema{
 			"security_group_id": &schema.Schema{
@@ -143,6 +146,9 @@ func resourceJDCloudNetworkSecurityGroupRules() *schema.Resource {
 
 func resourceJDCloudNetworkSecurityGroupRulesCreate(d *schema.ResourceData, m interface{}) error {
 
+	lockParent(m, d.Get("security_group_id").(string))
+	defer unlockParent(m, d.Get("security_group_id").(string))
+
 	if err := performSgRuleAttach(d, m, d.Get("security_group_rules").(*schema.Set)); err != nil {
 		return err
 	}
@@ -170,6 +176,7 @@ func resourceJDCloudNetworkSecurityGroupRulesRead(d *schema.ResourceData, meta i
 		return fmt.Errorf("[ERROR] resourceJDCloudNetworkSecurityGroupRulesRead failed  code:%d staus:%s message:%s ", resp.Error.Code, resp.Error.Status, resp.Error.Message)
 	}
 
+	d.Set("security_group_id", resp.Result.NetworkSecurityGroup.NetworkSecurityGroupId)
 	sgRules := resp.Result.NetworkSecurityGroup.SecurityGroupRules
 	sgRuleArray := make([]map[string]interface{}, 0, len(sgRules))
 	for _, rule := range sgRules {
@@ -195,6 +202,9 @@ func resourceJDCloudNetworkSecurityGroupRulesRead(d *schema.ResourceData, meta i
 
 func resourceJDCloudNetworkSecurityGroupRulesUpdate(d *schema.ResourceData, m interface{}) error {
 
+	lockParent(m, d.Get("security_group_id").(string))
+	defer unlockParent(m, d.Get("security_group_id").(string))
+
 	if d.HasChange("security_group_rules") {
 
 		pInterface, cInterface := d.GetChange("security_group_rules")
@@ -219,6 +229,9 @@ func resourceJDCloudNetworkSecurityGroupRulesUpdate(d *schema.ResourceData, m in
 
 func resourceJDCloudNetworkSecurityGroupRulesDelete(d *schema.ResourceData, m interface{}) error {
 
+	lockParent(m, d.Get("security_group_id").(string))
+	defer unlockParent(m, d.Get("security_group_id").(string))
+
 	if err := performSgRuleDetach(d, m, d.Get("security_group_rules").(*schema.Set)); err != nil {
 		return err
 	}
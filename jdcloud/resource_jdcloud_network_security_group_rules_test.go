@@ -79,6 +79,11 @@ func TestAccJDCloudSecurityGroupRule_basic(t *testing.T) {
 						"jdcloud_network_security_group_rules.sg-TEST-1", "security_group_rules.#", "2"),
 				),
 			},
+			{
+				ResourceName:      "jdcloud_network_security_group_rules.sg-TEST-1",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 		},
 	})
 
@@ -34,6 +34,7 @@ func resourceJDCloudOssBucket() *schema.Resource {
 				Optional: true,
 				Default:  "private",
 			},
+			"deletion_protection": deletionProtectionSchema(),
 		},
 	}
 }
@@ -114,6 +115,11 @@ func resourceJDCloudOssBucketUpdate(d *schema.ResourceData, m interface{}) error
 }
 
 func resourceJDCloudOssBucketDelete(d *schema.ResourceData, m interface{}) error {
+
+	if err := verifyDeletionProtection(d); err != nil {
+		return err
+	}
+
 	bucket := d.Get("bucket_name").(string)
 	client := getOssClient(m)
 	s3Input := &s3.DeleteBucketInput{
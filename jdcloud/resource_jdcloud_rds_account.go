@@ -14,7 +14,11 @@ func resourceJDCloudRDSAccount() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceJDCloudRDSAccountCreate,
 		Read:   resourceJDCloudRDSAccountRead,
+		Update: resourceJDCloudRDSAccountUpdate,
 		Delete: resourceJDCloudRDSAccountDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceJDCloudRDSAccountImport,
+		},
 
 		Schema: map[string]*schema.Schema{
 			"instance_id": &schema.Schema{
@@ -30,7 +34,6 @@ func resourceJDCloudRDSAccount() *schema.Resource {
 			"password": &schema.Schema{
 				Type:      schema.TypeString,
 				Required:  true,
-				ForceNew:  true,
 				Sensitive: true,
 			},
 		},
@@ -39,6 +42,9 @@ func resourceJDCloudRDSAccount() *schema.Resource {
 
 func resourceJDCloudRDSAccountCreate(d *schema.ResourceData, meta interface{}) error {
 
+	lockParent(meta, d.Get("instance_id").(string))
+	defer unlockParent(meta, d.Get("instance_id").(string))
+
 	config := meta.(*JDCloudConfig)
 	rdsClient := client.NewRdsClient(config.Credential)
 
@@ -49,7 +55,7 @@ func resourceJDCloudRDSAccountCreate(d *schema.ResourceData, meta interface{}) e
 		resp, err := rdsClient.CreateAccount(req)
 
 		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
-			d.SetId(resp.RequestID)
+			d.SetId(buildCompositeId(d.Get("instance_id").(string), d.Get("username").(string)))
 			return nil
 		}
 
@@ -90,6 +96,9 @@ func resourceJDCloudRDSAccountRead(d *schema.ResourceData, meta interface{}) err
 	for _, user := range resp.Result.Accounts {
 		if user.AccountName == d.Get("username").(string) {
 			d.Set("username", user.AccountName)
+
+			// Accounts created by earlier versions are identified by a request ID
+			d.SetId(buildCompositeId(d.Get("instance_id").(string), user.AccountName))
 			return nil
 		}
 	}
@@ -98,8 +107,58 @@ func resourceJDCloudRDSAccountRead(d *schema.ResourceData, meta interface{}) err
 	return nil
 }
 
+// Password can not be read back, it will be reset to the configured one
+// on the first apply after an import
+func resourceJDCloudRDSAccountUpdate(d *schema.ResourceData, meta interface{}) error {
+
+	lockParent(meta, d.Get("instance_id").(string))
+	defer unlockParent(meta, d.Get("instance_id").(string))
+
+	if d.HasChange("password") {
+
+		config := meta.(*JDCloudConfig)
+		rdsClient := client.NewRdsClient(config.Credential)
+		req := apis.NewResetPasswordRequest(config.Region, d.Get("instance_id").(string), d.Get("username").(string), d.Get("password").(string))
+
+		e := resource.Retry(5*time.Minute, func() *resource.RetryError {
+
+			resp, err := rdsClient.ResetPassword(req)
+
+			if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+				return nil
+			}
+
+			if connectionError(err) {
+				return resource.RetryableError(formatConnectionErrorMessage())
+			} else {
+				return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+			}
+		})
+
+		if e != nil {
+			return e
+		}
+	}
+	return resourceJDCloudRDSAccountRead(d, meta)
+}
+
+func resourceJDCloudRDSAccountImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+
+	parts, err := parseCompositeId(d.Id(), 2, "<instance_id>:<username>")
+	if err != nil {
+		return nil, err
+	}
+
+	d.Set("instance_id", parts[0])
+	d.Set("username", parts[1])
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceJDCloudRDSAccountDelete(d *schema.ResourceData, meta interface{}) error {
 
+	lockParent(meta, d.Get("instance_id").(string))
+	defer unlockParent(meta, d.Get("instance_id").(string))
+
 	config := meta.(*JDCloudConfig)
 	rdsClient := client.NewRdsClient(config.Credential)
 	req := apis.NewDeleteAccountRequest(config.Region, d.Get("instance_id").(string), d.Get("username").(string))
@@ -42,6 +42,12 @@ func TestAccJDCloudRDSAccount_basic(t *testing.T) {
 					resource.TestCheckResourceAttr("jdcloud_rds_account.rds-test1", "password", "DevOps2018"),
 				),
 			},
+			{
+				ResourceName:            "jdcloud_rds_account.rds-test1",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"password"},
+			},
 		},
 	})
 }
@@ -22,6 +22,9 @@ func resourceJDCloudRDSDatabase() *schema.Resource {
 		Create: resourceJDCloudRDSDatabaseCreate,
 		Read:   resourceJDCloudRDSDatabaseRead,
 		Delete: resourceJDCloudRDSDatabaseDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceJDCloudRDSDatabaseImport,
+		},
 
 		Schema: map[string]*schema.Schema{
 			"instance_id": &schema.Schema{
@@ -45,6 +48,9 @@ func resourceJDCloudRDSDatabase() *schema.Resource {
 
 func resourceJDCloudRDSDatabaseCreate(d *schema.ResourceData, meta interface{}) error {
 
+	lockParent(meta, d.Get("instance_id").(string))
+	defer unlockParent(meta, d.Get("instance_id").(string))
+
 	config := meta.(*JDCloudConfig)
 	rdsClient := client.NewRdsClient(config.Credential)
 
@@ -55,7 +61,7 @@ func resourceJDCloudRDSDatabaseCreate(d *schema.ResourceData, meta interface{})
 		resp, err := rdsClient.CreateDatabase(req)
 
 		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
-			d.SetId(resp.RequestID)
+			d.SetId(buildCompositeId(d.Get("instance_id").(string), d.Get("db_name").(string)))
 			return nil
 		}
 
@@ -88,6 +94,9 @@ func resourceJDCloudRDSDatabaseRead(d *schema.ResourceData, m interface{}) error
 					d.Set("db_name", db.DbName)
 					d.Set("character_set", db.CharacterSetName)
 
+					// Databases created by earlier versions are identified by a request ID
+					d.SetId(buildCompositeId(d.Get("instance_id").(string), db.DbName))
+
 					return nil
 				}
 			}
@@ -108,8 +117,23 @@ func resourceJDCloudRDSDatabaseRead(d *schema.ResourceData, m interface{}) error
 	})
 }
 
+func resourceJDCloudRDSDatabaseImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+
+	parts, err := parseCompositeId(d.Id(), 2, "<instance_id>:<db_name>")
+	if err != nil {
+		return nil, err
+	}
+
+	d.Set("instance_id", parts[0])
+	d.Set("db_name", parts[1])
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceJDCloudRDSDatabaseDelete(d *schema.ResourceData, meta interface{}) error {
 
+	lockParent(meta, d.Get("instance_id").(string))
+	defer unlockParent(meta, d.Get("instance_id").(string))
+
 	config := meta.(*JDCloudConfig)
 	rdsClient := client.NewRdsClient(config.Credential)
 
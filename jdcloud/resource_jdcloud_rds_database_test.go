@@ -42,6 +42,11 @@ func TestAccJDCloudRDSDatabase_basic(t *testing.T) {
 					resource.TestCheckResourceAttr("jdcloud_rds_database.db-TEST", "character_set", "utf8"),
 				),
 			},
+			{
+				ResourceName:      "jdcloud_rds_database.db-TEST",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 		},
 	})
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/charge/models"
+	common "github.com/jdcloud-api/jdcloud-sdk-go/services/common/models"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/rds/apis"
 	"github.com/jdcloud-api/jdcloud-sdk-go/services/rds/client"
 	rds "github.com/jdcloud-api/jdcloud-sdk-go/services/rds/models"
@@ -24,6 +25,16 @@ func resourceJDCloudRDSInstance() *schema.Resource {
 		Read:   resourceJDCloudRDSInstanceRead,
 		Update: resourceJDCloudRDSInstanceUpdate,
 		Delete: resourceJDCloudRDSInstanceDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		CustomizeDiff: chargeCustomizeDiff("charge", true),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(RDS_TIMEOUT * time.Second),
+			Update: schema.DefaultTimeout(RDS_TIMEOUT * time.Second),
+			Delete: schema.DefaultTimeout(RDS_TIMEOUT * time.Second),
+		},
 
 		Schema: map[string]*schema.Schema{
 			"instance_name": &schema.Schema{
@@ -80,20 +91,37 @@ func resourceJDCloudRDSInstance() *schema.Resource {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			"charge":              chargeSchemaWithLegacy(CHARGE_PREPAID_BY_DURATION, CHARGE_POSTPAID_BY_USAGE, CHARGE_POSTPAID_BY_DURATION),
+			"prepaid_on_destroy":  prepaidOnDestroySchema(),
+			"deletion_protection": deletionProtectionSchema(),
+			"tags": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
 			"charge_mode": &schema.Schema{
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				ForceNew:      true,
+				ValidateFunc:  validateStringCandidates(CHARGE_PREPAID_BY_DURATION, CHARGE_POSTPAID_BY_USAGE, CHARGE_POSTPAID_BY_DURATION),
+				Deprecated:    "Use charge.charge_mode instead",
+				ConflictsWith: []string{"charge"},
 			},
 			"charge_unit": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				ForceNew: true,
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ValidateFunc:  validateStringCandidates(CHARGE_UNIT_MONTH, CHARGE_UNIT_YEAR),
+				Deprecated:    "Use charge.charge_unit instead",
+				ConflictsWith: []string{"charge"},
 			},
 			"charge_duration": &schema.Schema{
-				Type:     schema.TypeInt,
-				Optional: true,
-				ForceNew: true,
+				Type:          schema.TypeInt,
+				Optional:      true,
+				ForceNew:      true,
+				Deprecated:    "Use charge.charge_duration instead",
+				ConflictsWith: []string{"charge"},
 			},
 		},
 	}
@@ -103,18 +131,10 @@ func resourceJDCloudRDSInstanceCreate(d *schema.ResourceData, meta interface{})
 
 	config := meta.(*JDCloudConfig)
 
-	chargeSpec := &models.ChargeSpec{}
-	chargeMode := d.Get("charge_mode").(string)
-	if chargeMode == "prepaid_by_duration" {
-		if _, ok := d.GetOk("charge_unit"); !ok {
-			return fmt.Errorf("[ERROR] resourceJDCloudRDSCreate failed in chargeMode parameter")
-		}
-		if _, ok := d.GetOk("charge_duration"); !ok {
-			return fmt.Errorf("[ERROR] resourceJDCloudRDSCreate failed in chargeDuration parameter")
-		}
-		chargeSpec = &models.ChargeSpec{ChargeMode: &chargeMode, ChargeUnit: GetStringAddr(d, "charge_unit"), ChargeDuration: GetIntAddr(d, "charge_duration")}
-	} else {
-		chargeSpec = &models.ChargeSpec{ChargeMode: &chargeMode}
+	// Postpaid by duration is applied if not specified
+	chargeSpec := chargeSpecWithLegacy(d)
+	if chargeSpec == nil {
+		chargeSpec = &models.ChargeSpec{}
 	}
 
 	req := apis.NewCreateInstanceRequest(
@@ -136,23 +156,24 @@ func resourceJDCloudRDSInstanceCreate(d *schema.ResourceData, meta interface{})
 		return validateVPC
 	}
 
-	instanceId := ""
 	rdsClient := client.NewRdsClient(config.Credential)
 
-	// Send a request here
-	err := resource.Retry(time.Minute, func() *resource.RetryError {
+	// Send a request here. CreateInstance accepts no client token, instances are looked up by name instead
+	lookup := func() ([]string, error) {
+		return queryRDSInstanceIdsByName(meta, d.Get("instance_name").(string))
+	}
+	instanceId, err := createWithLookup(d.Timeout(schema.TimeoutCreate), lookup, func() (string, *resource.RetryError) {
 
 		resp, err := rdsClient.CreateInstance(req)
 
 		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
-			instanceId = resp.Result.InstanceId
-			return nil
+			return resp.Result.InstanceId, nil
 		}
 
 		if connectionError(err) {
-			return resource.RetryableError(formatConnectionErrorMessage())
+			return "", resource.RetryableError(formatConnectionErrorMessage())
 		} else {
-			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+			return "", resource.NonRetryableError(formatErrorMessage(resp.Error, err))
 		}
 	})
 	if err != nil {
@@ -160,7 +181,7 @@ func resourceJDCloudRDSInstanceCreate(d *schema.ResourceData, meta interface{})
 	}
 
 	// Wait until RDS ready
-	err = rdsStatusWaiter(d, meta, instanceId, []string{RDS_CREATING, RDS_DELETED}, []string{RDS_READY})
+	err = rdsStatusWaiter(d, meta, instanceId, []string{RDS_CREATING, RDS_DELETED}, []string{RDS_READY}, d.Timeout(schema.TimeoutCreate))
 	if err != nil {
 		return err
 	}
@@ -172,6 +193,8 @@ func resourceJDCloudRDSInstanceCreate(d *schema.ResourceData, meta interface{})
 
 func resourceJDCloudRDSInstanceRead(d *schema.ResourceData, meta interface{}) error {
 
+	readDeletionProtection(d)
+
 	config := meta.(*JDCloudConfig)
 	req := apis.NewDescribeInstanceAttributesRequest(config.Region, d.Id())
 	rdsClient := client.NewRdsClient(config.Credential)
@@ -193,6 +216,9 @@ func resourceJDCloudRDSInstanceRead(d *schema.ResourceData, meta interface{}) er
 			d.Set("az", resp.Result.DbInstanceAttributes.AzId[0])
 			d.Set("vpc_id", resp.Result.DbInstanceAttributes.VpcId)
 			d.Set("subnet_id", resp.Result.DbInstanceAttributes.SubnetId)
+			d.Set("charge_mode", resp.Result.DbInstanceAttributes.Charge.ChargeMode)
+			d.Set("charge", chargeToTypeList(resp.Result.DbInstanceAttributes.Charge, d.Get("charge").([]interface{})))
+			d.Set("tags", rdsTagsToMap(resp.Result.DbInstanceAttributes.Tags))
 			return nil
 		}
 
@@ -211,6 +237,10 @@ func resourceJDCloudRDSInstanceRead(d *schema.ResourceData, meta interface{}) er
 
 func resourceJDCloudRDSInstanceUpdate(d *schema.ResourceData, meta interface{}) error {
 
+	// Accounts, databases and privileges can not be modified while resizing
+	lockParent(meta, d.Id())
+	defer unlockParent(meta, d.Id())
+
 	d.Partial(true)
 	defer d.Partial(false)
 
@@ -221,7 +251,7 @@ func resourceJDCloudRDSInstanceUpdate(d *schema.ResourceData, meta interface{})
 	if d.HasChange("instance_class") || d.HasChange("instance_storage_gb") {
 		req := apis.NewModifyInstanceSpecRequest(config.Region, d.Id(), d.Get("instance_class").(string), d.Get("instance_storage_gb").(int))
 
-		err := resource.Retry(3*time.Minute, func() *resource.RetryError {
+		err := resource.Retry(d.Timeout(schema.TimeoutUpdate), func() *resource.RetryError {
 			resp, err := rdsClient.ModifyInstanceSpec(req)
 
 			if resp != nil && resp.Error.Code == REQUEST_INVALID {
@@ -242,7 +272,7 @@ func resourceJDCloudRDSInstanceUpdate(d *schema.ResourceData, meta interface{})
 			return err
 		}
 
-		err = rdsStatusWaiter(d, meta, d.Id(), []string{RDS_UPDATING, RDS_UNCERTAIN}, []string{RDS_READY})
+		err = rdsStatusWaiter(d, meta, d.Id(), []string{RDS_UPDATING, RDS_UNCERTAIN}, []string{RDS_READY}, d.Timeout(schema.TimeoutUpdate))
 		if err != nil {
 			return err
 		}
@@ -256,12 +286,27 @@ func resourceJDCloudRDSInstanceUpdate(d *schema.ResourceData, meta interface{})
 
 func resourceJDCloudRDSInstanceDelete(d *schema.ResourceData, meta interface{}) error {
 
+	if err := verifyDeletionProtection(d); err != nil {
+		return err
+	}
+
+	// Prepaid RDS instances can not be deleted before they expire
+	item, _, err := rdsInstanceStatusRefreshFunc(d, meta, d.Id())()
+	if err != nil {
+		return err
+	}
+	if attributes, ok := item.(rds.DBInstanceAttribute); ok {
+		if proceed, err := prepaidDestroyAllowed(d, meta, attributes.Charge.ChargeMode); !proceed {
+			return err
+		}
+	}
+
 	config := meta.(*JDCloudConfig)
 	rdsClient := client.NewRdsClient(config.Credential)
 	req := apis.NewDeleteInstanceRequest(config.Region, d.Id())
 
 	// Send an DELETE request
-	err := resource.Retry(10*time.Minute, func() *resource.RetryError {
+	err = resource.Retry(d.Timeout(schema.TimeoutDelete), func() *resource.RetryError {
 
 		resp, err := rdsClient.DeleteInstance(req)
 
@@ -280,7 +325,45 @@ func resourceJDCloudRDSInstanceDelete(d *schema.ResourceData, meta interface{})
 		return err
 	}
 	// Wait until this Instance has been completely deleted
-	return rdsStatusWaiter(d, meta, d.Id(), []string{RDS_DELETING, RDS_READY}, []string{RDS_DELETED, RDS_UNCERTAIN})
+	return rdsStatusWaiter(d, meta, d.Id(), []string{RDS_DELETING, RDS_READY}, []string{RDS_DELETED, RDS_UNCERTAIN}, d.Timeout(schema.TimeoutDelete))
+}
+
+func rdsTagsToMap(tags []rds.Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[tag.Key] = tag.Value
+	}
+	return m
+}
+
+// Query ids of RDS instances with given name
+func queryRDSInstanceIdsByName(meta interface{}, name string) (ids []string, e error) {
+
+	config := meta.(*JDCloudConfig)
+	rdsClient := client.NewRdsClient(config.Credential)
+	req := apis.NewDescribeInstancesRequestWithAllParams(config.Region, nil, intAddr(RDS_PAGE_SIZE), []common.Filter{
+		{Name: "instanceName", Values: []string{name}},
+	}, nil)
+
+	e = resource.Retry(time.Minute, func() *resource.RetryError {
+
+		resp, err := rdsClient.DescribeInstances(req)
+
+		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			ids = []string{}
+			for _, instance := range resp.Result.DbInstances {
+				ids = append(ids, instance.InstanceId)
+			}
+			return nil
+		}
+
+		if connectionError(err) {
+			return resource.RetryableError(formatConnectionErrorMessage())
+		} else {
+			return resource.NonRetryableError(formatErrorMessage(resp.Error, err))
+		}
+	})
+	return ids, e
 }
 
 func rdsInstanceStatusRefreshFunc(d *schema.ResourceData, meta interface{}, rdsId string) resource.StateRefreshFunc {
@@ -335,15 +418,16 @@ func rdsInstanceStatusRefreshFunc(d *schema.ResourceData, meta interface{}, rdsI
 	}
 }
 
-func rdsStatusWaiter(d *schema.ResourceData, meta interface{}, id string, pending, target []string) (err error) {
+// RDS instances can only be described one by one, they are polled every poll_interval instead
+func rdsStatusWaiter(d *schema.ResourceData, meta interface{}, id string, pending, target []string, timeout time.Duration) (err error) {
 
 	stateConf := &resource.StateChangeConf{
-		Pending:    pending,
-		Target:     target,
-		Refresh:    rdsInstanceStatusRefreshFunc(d, meta, id),
-		Delay:      3 * time.Second,
-		Timeout:    10 * time.Minute,
-		MinTimeout: 1 * time.Second,
+		Pending:      pending,
+		Target:       target,
+		Refresh:      rdsInstanceStatusRefreshFunc(d, meta, id),
+		Delay:        3 * time.Second,
+		Timeout:      timeout,
+		PollInterval: meta.(*JDCloudConfig).PollInterval,
 	}
 	if _, err = stateConf.WaitForState(); err != nil {
 		return fmt.Errorf("[E] Failed in creatingDisk/Waiting disk,err message:%v", err)
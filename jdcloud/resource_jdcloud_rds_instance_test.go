@@ -76,6 +76,12 @@ func TestAccJDCloudRDSInstance_basic(t *testing.T) {
 						"jdcloud_rds_instance.tftest", "connection_mode"),
 				),
 			},
+			{
+				ResourceName:            "jdcloud_rds_instance.tftest",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"charge_unit", "charge_duration"},
+			},
 		},
 	})
 }
@@ -99,6 +99,9 @@ func resourceJDCloudRDSPrivilege() *schema.Resource {
 		Read:   resourceJDCloudRDSPrivilegeRead,
 		Update: resourceJDCloudRDSPrivilegeUpdate,
 		Delete: resourceJDCloudRDSPrivilegeDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceJDCloudRDSPrivilegeImport,
+		},
 
 		Schema: map[string]*schema.Schema{
 			"instance_id": &schema.Schema{
@@ -123,11 +126,14 @@ func resourceJDCloudRDSPrivilege() *schema.Resource {
 
 func resourceJDCloudRDSPrivilegeCreate(d *schema.ResourceData, m interface{}) error {
 
+	lockParent(m, d.Get("instance_id").(string))
+	defer unlockParent(m, d.Get("instance_id").(string))
+
 	if err := performAttachDB(d, m, d.Get("account_privilege").(*schema.Set)); err != nil {
 		return err
 	}
 
-	d.SetId(d.Get("username").(string))
+	d.SetId(buildCompositeId(d.Get("instance_id").(string), d.Get("username").(string)))
 	return resourceJDCloudRDSPrivilegeRead(d, m)
 }
 
@@ -167,6 +173,9 @@ func resourceJDCloudRDSPrivilegeRead(d *schema.ResourceData, meta interface{}) e
 			if err := d.Set("account_privilege", latestPrivileges); err != nil {
 				return fmt.Errorf("[ERROR] Failed in resourceJDCloudRDSPrivilegeRead,reasons:%s", err.Error())
 			}
+
+			// Privileges created by earlier versions are identified by username only
+			d.SetId(buildCompositeId(d.Get("instance_id").(string), user.AccountName))
 			return nil
 
 		}
@@ -178,6 +187,9 @@ func resourceJDCloudRDSPrivilegeRead(d *schema.ResourceData, meta interface{}) e
 
 func resourceJDCloudRDSPrivilegeUpdate(d *schema.ResourceData, m interface{}) error {
 
+	lockParent(m, d.Get("instance_id").(string))
+	defer unlockParent(m, d.Get("instance_id").(string))
+
 	d.Partial(true)
 	defer d.Partial(false)
 
@@ -201,8 +213,23 @@ func resourceJDCloudRDSPrivilegeUpdate(d *schema.ResourceData, m interface{}) er
 	return resourceJDCloudRDSPrivilegeRead(d, m)
 }
 
+func resourceJDCloudRDSPrivilegeImport(d *schema.ResourceData, m interface{}) ([]*schema.ResourceData, error) {
+
+	parts, err := parseCompositeId(d.Id(), 2, "<instance_id>:<username>")
+	if err != nil {
+		return nil, err
+	}
+
+	d.Set("instance_id", parts[0])
+	d.Set("username", parts[1])
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceJDCloudRDSPrivilegeDelete(d *schema.ResourceData, m interface{}) error {
 
+	lockParent(m, d.Get("instance_id").(string))
+	defer unlockParent(m, d.Get("instance_id").(string))
+
 	if err := performDetachDB(d, m, dbNameList(d.Get("account_privilege").(*schema.Set))); err != nil {
 		return err
 	}
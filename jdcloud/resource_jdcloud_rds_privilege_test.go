@@ -63,6 +63,11 @@ func TestAccJDCloudRDSPrivilege_basic(t *testing.T) {
 					resource.TestCheckResourceAttr("jdcloud_rds_privilege.pri-test", "account_privilege.#", "3"),
 				),
 			},
+			{
+				ResourceName:      "jdcloud_rds_privilege.pri-test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 		},
 	})
 }
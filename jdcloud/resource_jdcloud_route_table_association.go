@@ -16,6 +16,9 @@ func resourceJDCloudRouteTableAssociation() *schema.Resource {
 		Read:   resourceRouteTableAssociationRead,
 		Update: resourceRouteTableAssociationUpdate,
 		Delete: resourceRouteTableAssociationDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
 
 		Schema: map[string]*schema.Schema{
 
@@ -39,6 +42,9 @@ func resourceJDCloudRouteTableAssociation() *schema.Resource {
 
 func resourceRouteTableAssociationCreate(d *schema.ResourceData, meta interface{}) error {
 
+	lockParent(meta, d.Get("route_table_id").(string))
+	defer unlockParent(meta, d.Get("route_table_id").(string))
+
 	attachList := typeSetToStringArray(d.Get("subnet_id").(*schema.Set))
 	routeTableId := d.Get("route_table_id").(string)
 
@@ -62,6 +68,7 @@ func resourceRouteTableAssociationRead(d *schema.ResourceData, meta interface{})
 
 		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
 
+			d.Set("route_table_id", resp.Result.RouteTable.RouteTableId)
 			if err := d.Set("subnet_id", resp.Result.RouteTable.SubnetIds); err != nil {
 				return resource.NonRetryableError(formatArraySetErrorMessage(err))
 			}
@@ -83,6 +90,9 @@ func resourceRouteTableAssociationRead(d *schema.ResourceData, meta interface{})
 
 func resourceRouteTableAssociationUpdate(d *schema.ResourceData, m interface{}) error {
 
+	lockParent(m, d.Get("route_table_id").(string))
+	defer unlockParent(m, d.Get("route_table_id").(string))
+
 	d.Partial(true)
 	defer d.Partial(false)
 
@@ -113,6 +123,9 @@ func resourceRouteTableAssociationUpdate(d *schema.ResourceData, m interface{})
 
 func resourceRouteTableAssociationDelete(d *schema.ResourceData, meta interface{}) error {
 
+	lockParent(meta, d.Get("route_table_id").(string))
+	defer unlockParent(meta, d.Get("route_table_id").(string))
+
 	subnetIds := typeSetToStringArray(d.Get("subnet_id").(*schema.Set))
 
 	if err := performSubnetDetach(d, meta, subnetIds); err != nil {
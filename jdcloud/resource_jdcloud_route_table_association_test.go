@@ -38,6 +38,11 @@ func TestAccJDCloudRouteTableAssociation_basic(t *testing.T) {
 					resource.TestCheckResourceAttr("jdcloud_route_table_association.route-table-association-TEST-1", "subnet_id.#", "1"),
 				),
 			},
+			{
+				ResourceName:      "jdcloud_route_table_association.route-table-association-TEST-1",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 		},
 	})
 }
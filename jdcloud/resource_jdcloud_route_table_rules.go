@@ -122,6 +122,9 @@ func resourceJDCloudRouteTableRules() *schema.Resource {
 		Read:   resourceRouteTableRulesRead,
 		Update: resourceRouteTableRulesUpdate,
 		Delete: resourceRouteTableRulesDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
 
 		Schema: map[string]*schema.Schema{
 			"route_table_id": {
@@ -166,6 +169,9 @@ func resourceJDCloudRouteTableRules() *schema.Resource {
 
 func resourceRouteTableRulesCreate(d *schema.ResourceData, m interface{}) error {
 
+	lockParent(m, d.Get("route_table_id").(string))
+	defer unlockParent(m, d.Get("route_table_id").(string))
+
 	tableId := d.Get("route_table_id").(string)
 	attachList := typeSetToRouteRuleArray(d.Get("rule_specs").(*schema.Set))
 
@@ -190,6 +196,7 @@ func resourceRouteTableRulesRead(d *schema.ResourceData, m interface{}) error {
 		resp, err := vpcClient.DescribeRouteTable(req)
 
 		if err == nil && resp.Error.Code == REQUEST_COMPLETED {
+			d.Set("route_table_id", resp.Result.RouteTable.RouteTableId)
 			ruleMap := ruleMap(resp.Result.RouteTable.RouteTableRules[1:])
 			if err := d.Set("rule_specs", ruleMap); err != nil {
 				return resource.NonRetryableError(err)
@@ -213,6 +220,9 @@ func resourceRouteTableRulesRead(d *schema.ResourceData, m interface{}) error {
 
 func resourceRouteTableRulesUpdate(d *schema.ResourceData, m interface{}) error {
 
+	lockParent(m, d.Get("route_table_id").(string))
+	defer unlockParent(m, d.Get("route_table_id").(string))
+
 	d.Partial(true)
 	defer d.Partial(false)
 
@@ -243,6 +253,9 @@ func resourceRouteTableRulesUpdate(d *schema.ResourceData, m interface{}) error
 
 func resourceRouteTableRulesDelete(d *schema.ResourceData, m interface{}) error {
 
+	lockParent(m, d.Get("route_table_id").(string))
+	defer unlockParent(m, d.Get("route_table_id").(string))
+
 	config := m.(*JDCloudConfig)
 	idList := ruleIdList(d.Get("rule_specs").(*schema.Set))
 	routeTableRulesClient := client.NewVpcClient(config.Credential)
@@ -73,6 +73,11 @@ func TestAccJDCloudRouteTableRules_basic(t *testing.T) {
 					resource.TestCheckResourceAttr("jdcloud_route_table_rules.rule-TEST-1", "rule_specs.#", "2"),
 				),
 			},
+			{
+				ResourceName:      "jdcloud_route_table_rules.rule-TEST-1",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
 		},
 	})
 
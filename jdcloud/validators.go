@@ -5,6 +5,7 @@ import (
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/jdcloud-api/jdcloud-sdk-go/core"
+	disk "github.com/jdcloud-api/jdcloud-sdk-go/services/disk/models"
 	vpcApis "github.com/jdcloud-api/jdcloud-sdk-go/services/vpc/apis"
 	vpcClient "github.com/jdcloud-api/jdcloud-sdk-go/services/vpc/client"
 	"github.com/satori/go.uuid"
@@ -47,7 +48,9 @@ func validateStringNoEmpty(v interface{}, k string) (s []string, errs []error) {
 	return
 }
 
-func diskClientTokenDefault() string {
+// Generate one token per create operation and keep it across retries,
+// so that a retried request returns what has been created instead of creating another one
+func clientTokenDefault() string {
 	var clientToken string
 	nonce, _ := uuid.NewV4()
 	clientToken = nonce.String()
@@ -187,6 +190,17 @@ func validateDiskSize() schema.SchemaValidateFunc {
 	}
 }
 
+func validateIntRange(min, max int) schema.SchemaValidateFunc {
+	return func(v interface{}, k string) (ws []string, errors []error) {
+
+		value := v.(int)
+		if value < min || value > max {
+			errors = append(errors, fmt.Errorf("[ERROR] Valid %s varies from %d~%d, yours: %d", k, min, max, value))
+		}
+		return
+	}
+}
+
 func validateStringCandidates(c ...string) schema.SchemaValidateFunc {
 	return func(v interface{}, k string) (ws []string, errors []error) {
 
@@ -262,6 +276,53 @@ func RetryWithParamsSpecified(frequency, timeout time.Duration, f resource.Retry
 	return resultErr
 }
 
+// Create a resource whose API accepts no client token, level 1
+// Resources found by lookup beforehand are remembered. Once a request ends up timing out it may have
+// succeeded, lookup runs again before retrying and the newly appeared resource is adopted
+func createWithLookup(timeout time.Duration, lookup func() ([]string, error), create func() (string, *resource.RetryError)) (id string, e error) {
+
+	existing, e := lookup()
+	if e != nil {
+		return "", e
+	}
+	known := make(map[string]bool, len(existing))
+	for _, i := range existing {
+		known[i] = true
+	}
+
+	ambiguous := false
+	e = resource.Retry(timeout, func() *resource.RetryError {
+
+		if ambiguous {
+			found, err := lookup()
+			if err != nil {
+				return resource.NonRetryableError(err)
+			}
+			created := []string{}
+			for _, i := range found {
+				if !known[i] {
+					created = append(created, i)
+				}
+			}
+			if len(created) == 1 {
+				id = created[0]
+				return nil
+			}
+			if len(created) > 1 {
+				return resource.NonRetryableError(fmt.Errorf("[ERROR] Can not tell which one has been created among %v, import it and remove the others", created))
+			}
+		}
+
+		var rerr *resource.RetryError
+		id, rerr = create()
+		if rerr != nil && rerr.Retryable {
+			ambiguous = true
+		}
+		return rerr
+	})
+	return id, e
+}
+
 func randomStringWithLength(i int) string {
 
 	b := make([]rune, i)
@@ -349,3 +410,88 @@ func dealWithErr(e error) (bool, bool) {
 
 	return false, false
 }
+
+/*
+	Child resources such as RDS accounts and databases are identified by
+	their parent ID together with their own name, e.g. "mysql-xxx:devops".
+	These two functions build and parse such composite IDs
+*/
+
+const COMPOSITE_ID_SEPARATOR = ":"
+
+func buildCompositeId(parts ...string) string {
+	return strings.Join(parts, COMPOSITE_ID_SEPARATOR)
+}
+
+func parseCompositeId(id string, count int, format string) ([]string, error) {
+
+	parts := strings.SplitN(id, COMPOSITE_ID_SEPARATOR, count)
+	if len(parts) != count {
+		return nil, fmt.Errorf("[ERROR] Invalid ID '%s', expected format: %s", id, format)
+	}
+	for _, part := range parts {
+		if len(part) == 0 {
+			return nil, fmt.Errorf("[ERROR] Invalid ID '%s', expected format: %s", id, format)
+		}
+	}
+	return parts, nil
+}
+
+// Attributes used to hold a single string have become lists or sets, wrap their values accordingly
+func stringToListStateUpgradeFunc(keys ...string) schema.StateUpgradeFunc {
+	return func(rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+
+		for _, key := range keys {
+			v, ok := rawState[key].(string)
+			if !ok {
+				continue
+			}
+			if len(v) == 0 {
+				rawState[key] = []interface{}{}
+			} else {
+				rawState[key] = []interface{}{v}
+			}
+		}
+		return rawState, nil
+	}
+}
+
+// Used in StateUpgraders, replace the legacy ID (usually a request ID)
+// with a composite one built from the attributes listed in keys
+func compositeIdStateUpgradeFunc(keys ...string) schema.StateUpgradeFunc {
+	return func(rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+
+		parts := []string{}
+		for _, key := range keys {
+			v, ok := rawState[key].(string)
+			if !ok || len(v) == 0 {
+				return nil, fmt.Errorf("[ERROR] Failed in upgrading state, attribute '%s' is missing", key)
+			}
+			parts = append(parts, v)
+		}
+
+		rawState["id"] = buildCompositeId(parts...)
+		return rawState, nil
+	}
+}
+
+// Same regions as accepted by the provider, see regionCn
+func validateRegion() schema.SchemaValidateFunc {
+	return func(v interface{}, k string) (ws []string, errors []error) {
+
+		if _, ok := regionCn[v.(string)]; !ok {
+			errors = append(errors, fmt.Errorf("[ERROR] Invalid %s '%s'", k, v.(string)))
+		}
+		return
+	}
+}
+
+// Tags are read only for now, the vendored SDK offers no API to write them.
+// Instances share the tag model of disks
+func tagsToMap(tags []disk.Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[tag.Key] = tag.Value
+	}
+	return m
+}